@@ -0,0 +1,438 @@
+package jlang
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Assemble assembles top level instructions and constants from source in
+// assembly form, see AssembleBytecode.
+func Assemble(src string) (Instructions, []Constant, error) {
+	bytecode, err := AssembleBytecode(src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return bytecode.Instructions, bytecode.Constants, nil
+}
+
+// AssembleBytecode assembles bytecode from source in assembly form.
+// Each line holds an instruction, a label, a directive or nothing, and ';' starts a comment.
+//
+//	.const 1              ; appends constant 1 to constant pool
+//	.const "hello"        ; string, true, false, null and fn#<index> constants work too
+//	loop:                 ; label at offset of next instruction
+//	OpConstant 0          ; opcode name followed by operands
+//	OpJumpNotTruthy end   ; label as operand is replaced with its offset
+//	OpJump loop
+//	end:
+//	.func add 2 2         ; compiled function with 2 parameters and 2 locals
+//	OpGetLocal 0
+//	OpReturnValue
+//	.end
+//
+// Operands that overflow their widths are encoded with OpWide prefix, which may also be
+// written explicitly. Offsets leading lines of Instructions.String are ignored so that
+// disassembly can be assembled again. Labels are local to the function they appear in.
+// Exception table entries are added with .handler <start> <end> <target>, whose
+// operands are labels or offsets of the function or top level code it appears in.
+func AssembleBytecode(src string) (*Bytecode, error) {
+	a := &assembler{
+		bytecode: &Bytecode{
+			Constants: []Constant{},
+			Functions: []*CompiledFunction{},
+			Symbols:   []Symbol{},
+			Lines:     []Line{},
+		},
+	}
+	a.unit = a.newUnit(nil)
+
+	for i, line := range strings.Split(src, "\n") {
+		a.line = i + 1
+		if err := a.assembleLine(line); err != nil {
+			return nil, err
+		}
+	}
+
+	if a.unit.function != nil {
+		return nil, fmt.Errorf(".func %s is not closed with .end, line %d", a.unit.function.Name, a.line)
+	}
+
+	ins, handlers, err := a.unit.link()
+	if err != nil {
+		return nil, err
+	}
+
+	a.bytecode.Instructions, a.bytecode.Handlers = ins, handlers
+	return a.bytecode, nil
+}
+
+type assembler struct {
+	bytecode *Bytecode
+
+	// unit being assembled and top level code while a function is assembled
+	unit *asmUnit
+	top  *asmUnit
+
+	line int
+}
+
+// asmUnit is top level code or a function being assembled.
+type asmUnit struct {
+	function *CompiledFunction
+	items    []*asmItem
+	labels   map[string]int
+
+	// handlers with start, end and target operands
+	handlers []*asmItem
+}
+
+// asmItem is an instruction whose label operands are not resolved yet.
+type asmItem struct {
+	op       Opcode
+	desc     OpcodeDesc
+	operands []string
+	wide     bool
+	line     int
+}
+
+func (a *assembler) newUnit(fn *CompiledFunction) *asmUnit {
+	return &asmUnit{
+		function: fn,
+		items:    []*asmItem{},
+		labels:   make(map[string]int),
+		handlers: []*asmItem{},
+	}
+}
+
+func (a *assembler) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s, line %d", fmt.Sprintf(format, args...), a.line)
+}
+
+func (a *assembler) assembleLine(line string) error {
+	line = strings.TrimSpace(line)
+
+	if strings.HasPrefix(line, ".const") {
+		return a.assembleConstant(strings.TrimSpace(strings.TrimPrefix(line, ".const")))
+	}
+
+	if i := strings.Index(line, ";"); i >= 0 {
+		line = line[:i]
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	switch fields[0] {
+	case ".func":
+		return a.assembleFunction(fields[1:])
+	case ".end":
+		return a.assembleEnd(fields[1:])
+	case ".handler":
+		return a.assembleHandler(fields[1:])
+	}
+
+	if strings.HasSuffix(fields[0], ":") {
+		label := strings.TrimSuffix(fields[0], ":")
+		if label == "" {
+			return a.errorf("label needs a name")
+		}
+
+		if _, ok := a.unit.labels[label]; ok {
+			return a.errorf("label %s redefined", label)
+		}
+
+		// labels hold index of following instruction until offsets are known
+		a.unit.labels[label] = len(a.unit.items)
+		fields = fields[1:]
+	}
+
+	if len(fields) > 0 && isOffset(fields[0]) {
+		fields = fields[1:]
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return a.assembleInstruction(fields)
+}
+
+func (a *assembler) assembleInstruction(fields []string) error {
+	item := &asmItem{line: a.line}
+
+	if fields[0] == OpWide.String() {
+		item.wide = true
+		fields = fields[1:]
+
+		if len(fields) == 0 {
+			return a.errorf("OpWide needs an instruction")
+		}
+	}
+
+	op, ok := opcodeNamed(fields[0])
+	if !ok {
+		return a.errorf("unknown opcode %s", fields[0])
+	}
+
+	item.op = op
+	item.desc = opDictionary[op]
+	item.operands = fields[1:]
+
+	if item.wide && (op == OpWide || len(item.desc.OperandsWidth) == 0) {
+		return a.errorf("OpWide is followed by %s which has no operands", item.desc.Name)
+	}
+
+	if len(item.operands) != len(item.desc.OperandsWidth) {
+		return a.errorf("%s takes %d operands, got %d",
+			item.desc.Name, len(item.desc.OperandsWidth), len(item.operands))
+	}
+
+	a.unit.items = append(a.unit.items, item)
+	return nil
+}
+
+func (a *assembler) assembleConstant(value string) error {
+	c, rest, err := parseConstant(value)
+	if err != nil {
+		return a.errorf("%s", err)
+	}
+
+	rest = strings.TrimSpace(rest)
+	if rest != "" && !strings.HasPrefix(rest, ";") {
+		return a.errorf("unexpected %s after constant", rest)
+	}
+
+	a.bytecode.Constants = append(a.bytecode.Constants, c)
+	return nil
+}
+
+// assembleFunction starts function of form .func <name> <params> <locals>
+func (a *assembler) assembleFunction(args []string) error {
+	if a.unit.function != nil {
+		return a.errorf(".func %s is not closed with .end", a.unit.function.Name)
+	}
+
+	if len(args) != 3 {
+		return a.errorf(".func takes name, number of parameters and number of locals, got %d arguments", len(args))
+	}
+
+	params, err := strconv.Atoi(args[1])
+	if err != nil || params < 0 {
+		return a.errorf("invalid number of parameters %s", args[1])
+	}
+
+	locals, err := strconv.Atoi(args[2])
+	if err != nil || locals < 0 {
+		return a.errorf("invalid number of locals %s", args[2])
+	}
+
+	a.top = a.unit
+	a.unit = a.newUnit(&CompiledFunction{
+		Name:      args[0],
+		NumParams: params,
+		NumLocals: locals,
+		Lines:     []Line{},
+	})
+
+	return nil
+}
+
+func (a *assembler) assembleEnd(args []string) error {
+	if a.unit.function == nil {
+		return a.errorf(".end without .func")
+	}
+
+	if len(args) != 0 {
+		return a.errorf("unexpected %s after .end", args[0])
+	}
+
+	ins, handlers, err := a.unit.link()
+	if err != nil {
+		return err
+	}
+
+	a.unit.function.Instructions, a.unit.function.Handlers = ins, handlers
+	a.bytecode.Functions = append(a.bytecode.Functions, a.unit.function)
+	a.unit = a.top
+	return nil
+}
+
+// assembleHandler adds handler of form .handler <start> <end> <target>
+func (a *assembler) assembleHandler(args []string) error {
+	if len(args) != 3 {
+		return a.errorf(".handler takes start, end and target, got %d arguments", len(args))
+	}
+
+	a.unit.handlers = append(a.unit.handlers, &asmItem{operands: args, line: a.line})
+	return nil
+}
+
+// link resolves labels and encodes instructions and handlers. Instructions start narrow
+// and those with an operand which does not fit are widened until offsets settle.
+func (u *asmUnit) link() (Instructions, []Handler, error) {
+	offsets := make([]int, len(u.items)+1)
+	operands := make([][]int, len(u.items))
+
+	for {
+		offset := 0
+		for i, item := range u.items {
+			offsets[i] = offset
+			offset += 1 + operandsWidth(item.desc)
+			if item.wide {
+				offset += 1 + operandsWidth(item.desc)
+			}
+		}
+		offsets[len(u.items)] = offset
+
+		widened := false
+		for i, item := range u.items {
+			values, err := u.resolve(item, offsets)
+			if err != nil {
+				return nil, nil, err
+			}
+			operands[i] = values
+
+			if !item.wide && !fits(item.desc, values) {
+				item.wide = true
+				widened = true
+			}
+		}
+
+		if !widened {
+			break
+		}
+	}
+
+	ins := Instructions{}
+	for i, item := range u.items {
+		desc := item.desc
+		if item.wide {
+			desc = Wide(desc)
+			ins = append(ins, byte(OpWide))
+		}
+
+		if !fits(desc, operands[i]) {
+			return nil, nil, fmt.Errorf("operands %v do not fit in %s, line %d", operands[i], item.desc.Name, item.line)
+		}
+
+		ins = append(ins, encode(item.op, desc, operands[i])...)
+	}
+
+	handlers := []Handler{}
+	for _, h := range u.handlers {
+		values, err := u.resolve(h, offsets)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		handlers = append(handlers, Handler{Start: values[0], End: values[1], Target: values[2]})
+	}
+
+	return ins, handlers, nil
+}
+
+func (u *asmUnit) resolve(item *asmItem, offsets []int) ([]int, error) {
+	values := make([]int, len(item.operands))
+
+	for i, operand := range item.operands {
+		if index, ok := u.labels[operand]; ok {
+			values[i] = offsets[index]
+			continue
+		}
+
+		value, err := strconv.Atoi(operand)
+		if err != nil {
+			if isIdentifier(operand) {
+				return nil, fmt.Errorf("undefined label %s, line %d", operand, item.line)
+			}
+			return nil, fmt.Errorf("invalid operand %s, line %d", operand, item.line)
+		}
+
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+// parseConstant parses constant at the start of s and returns rest of s.
+func parseConstant(s string) (Constant, string, error) {
+	if strings.HasPrefix(s, `"`) {
+		quoted, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid string constant %s", s)
+		}
+
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid string constant %s", s)
+		}
+
+		return StringConstant(value), s[len(quoted):], nil
+	}
+
+	word, rest := s, ""
+	if i := strings.IndexAny(s, " \t;"); i >= 0 {
+		word, rest = s[:i], s[i:]
+	}
+
+	switch {
+	case word == "":
+		return nil, "", fmt.Errorf(".const needs a value")
+	case word == "true" || word == "false":
+		return BooleanConstant(word == "true"), rest, nil
+	case word == "null":
+		return NullConstant{}, rest, nil
+	case strings.HasPrefix(word, "fn#"):
+		index, err := strconv.Atoi(strings.TrimPrefix(word, "fn#"))
+		if err != nil || index < 0 {
+			return nil, "", fmt.Errorf("invalid function constant %s", word)
+		}
+		return FunctionConstant(index), rest, nil
+	}
+
+	value, err := strconv.ParseInt(word, 10, 64)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid constant %s", word)
+	}
+
+	return IntegerConstant(value), rest, nil
+}
+
+func opcodeNamed(name string) (Opcode, bool) {
+	for op, desc := range opDictionary {
+		if desc.Name == name {
+			return op, true
+		}
+	}
+
+	return 0, false
+}
+
+// isOffset returns whether s is an offset as printed by Instructions.String.
+func isOffset(s string) bool {
+	if len(s) < 4 {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isIdentifier(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isLetter(s[i]) && (i == 0 || !isDigit(s[i])) {
+			return false
+		}
+	}
+
+	return s != ""
+}
@@ -0,0 +1,198 @@
+package jlang
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAssemble(t *testing.T) {
+	tests := []struct {
+		input             string
+		expectedIns       Instructions
+		expectedConstants []Constant
+	}{
+		{
+			`
+			.const 1
+			.const 2 ; second constant
+			OpConstant 0
+			OpConstant 1
+			OpAdd
+			OpPop
+			`,
+			concat(Make(OpConstant, 0), Make(OpConstant, 1), Make(OpAdd), Make(OpPop)),
+			[]Constant{IntegerConstant(1), IntegerConstant(2)},
+		},
+		{
+			`
+			.const "a;b \"c\"" ; comment
+			.const true
+			.const false
+			.const null
+			.const -7
+			.const fn#0
+			`,
+			Instructions{},
+			[]Constant{
+				StringConstant(`a;b "c"`), BooleanConstant(true), BooleanConstant(false),
+				NullConstant{}, IntegerConstant(-7), FunctionConstant(0),
+			},
+		},
+		{
+			`
+			loop: OpTrue
+			OpJumpNotTruthy end
+			OpJump loop
+			end:
+			`,
+			concat(Make(OpTrue), Make(OpJumpNotTruthy, 7), Make(OpJump, 0)),
+			[]Constant{},
+		},
+		{
+			`
+			OpConstant 70000
+			OpWide OpGetLocal 1
+			OpGetLocal 256
+			`,
+			concat(Make(OpConstant, 70000), Instructions{byte(OpWide), byte(OpGetLocal), 0, 1}, Make(OpGetLocal, 256)),
+			[]Constant{},
+		},
+		{
+			// disassembly is assembled again
+			concat(Make(OpConstant, 70000), Make(OpGetLocal, 1), Make(OpPop)).String(),
+			concat(Make(OpConstant, 70000), Make(OpGetLocal, 1), Make(OpPop)),
+			[]Constant{},
+		},
+	}
+
+	for _, tt := range tests {
+		ins, constants, err := Assemble(tt.input)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", tt.input, err)
+			continue
+		}
+
+		if string(ins) != string(tt.expectedIns) {
+			t.Errorf("instructions wrong.\nwant=%q\ngot=%q", tt.expectedIns.String(), ins.String())
+		}
+
+		if !reflect.DeepEqual(constants, tt.expectedConstants) {
+			t.Errorf("constants wrong. expected=%v, got=%v", tt.expectedConstants, constants)
+		}
+	}
+}
+
+// Jumps over a wide instruction move when jumps themselves must become wide.
+func TestAssemble_WideJump(t *testing.T) {
+	src := "OpJump end\n" + strings.Repeat("OpNull\nOpPop\n", 40000) + "end:"
+
+	ins, _, err := Assemble(src)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	jump, err := ReadInstruction(ins, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !jump.Wide || jump.Operands[0] != len(ins) {
+		t.Errorf("jump wrong. expected wide jump to %d, got=%s", len(ins), jump)
+	}
+}
+
+func TestAssembleBytecode(t *testing.T) {
+	input := `
+	.const 10
+	.const fn#0
+	OpConstant 1
+	OpConstant 0
+	OpCall 1
+	OpPop
+
+	.func double 1 1
+	start:
+	OpGetLocal 0
+	OpGetLocal 0
+	OpAdd
+	OpReturnValue
+	catch:
+	OpReturnValue
+	.handler start catch catch
+	.end
+	`
+
+	bytecode, err := AssembleBytecode(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := Verify(bytecode); err != nil {
+		t.Fatalf("assembled bytecode does not verify: %s", err)
+	}
+
+	if len(bytecode.Functions) != 1 {
+		t.Fatalf("bytecode does not have 1 function. got=%d", len(bytecode.Functions))
+	}
+
+	fn := bytecode.Functions[0]
+	if fn.Name != "double" || fn.NumParams != 1 || fn.NumLocals != 1 {
+		t.Errorf("function wrong. got=%+v", fn)
+	}
+
+	expected := concat(Make(OpGetLocal, 0), Make(OpGetLocal, 0), Make(OpAdd), Make(OpReturnValue), Make(OpReturnValue))
+	if string(fn.Instructions) != string(expected) {
+		t.Errorf("function instructions wrong.\nwant=%q\ngot=%q", expected.String(), fn.Instructions.String())
+	}
+
+	handlers := []Handler{{Start: 0, End: 6, Target: 6}}
+	if !reflect.DeepEqual(fn.Handlers, handlers) {
+		t.Errorf("function handlers wrong. expected=%v, got=%v", handlers, fn.Handlers)
+	}
+
+	if len(bytecode.Handlers) != 0 {
+		t.Errorf("top level code has handlers. got=%v", bytecode.Handlers)
+	}
+}
+
+func TestAssemble_Errors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"OpFoo", "unknown opcode OpFoo, line 1"},
+		{"\nOpConstant", "OpConstant takes 1 operands, got 0, line 2"},
+		{"OpPop 1", "OpPop takes 0 operands, got 1, line 1"},
+		{"OpJump nowhere", "undefined label nowhere, line 1"},
+		{"OpConstant x1!", "invalid operand x1!, line 1"},
+		{"a:\na:", "label a redefined, line 2"},
+		{"OpGetLocal 4294967296", "operands [4294967296] do not fit in OpGetLocal, line 1"},
+		{"OpWide OpPop", "OpWide is followed by OpPop which has no operands, line 1"},
+		{"OpWide", "OpWide needs an instruction, line 1"},
+		{".const", ".const needs a value, line 1"},
+		{".const abc", "invalid constant abc, line 1"},
+		{`.const "abc`, `invalid string constant "abc, line 1`},
+		{".const 1 2", "unexpected 2 after constant, line 1"},
+		{".const fn#x", "invalid function constant fn#x, line 1"},
+		{".func f 1", ".func takes name, number of parameters and number of locals, got 2 arguments, line 1"},
+		{".func f x 1", "invalid number of parameters x, line 1"},
+		{".func f 1 1\n.func g 1 1", ".func f is not closed with .end, line 2"},
+		{".func f 1 1\nOpReturn", ".func f is not closed with .end, line 2"},
+		{".end", ".end without .func, line 1"},
+		{".handler a b", ".handler takes start, end and target, got 2 arguments, line 1"},
+		{"a:\nOpNull\n.handler a b a", "undefined label b, line 3"},
+	}
+
+	for _, tt := range tests {
+		_, _, err := Assemble(tt.input)
+		if err == nil {
+			t.Errorf("expected error %q for %q. got none", tt.expectedError, tt.input)
+			continue
+		}
+
+		if err.Error() != tt.expectedError {
+			t.Errorf("error wrong for %q. expected=%q, got=%q", tt.input, tt.expectedError, err.Error())
+		}
+	}
+}
@@ -2,6 +2,8 @@ package ast
 
 import (
 	"bytes"
+	"fmt"
+	"path"
 
 	"strings"
 
@@ -23,6 +25,12 @@ type Expression interface {
 	expressionNode()
 }
 
+// Pattern is a binding target of destructuring.
+type Pattern interface {
+	Node
+	patternNode()
+}
+
 type Program struct {
 	Statements []Statement
 }
@@ -51,6 +59,7 @@ type Identifier struct {
 }
 
 func (i *Identifier) expressionNode() {}
+func (i *Identifier) patternNode()    {}
 
 func (i *Identifier) TokenValue() string {
 	return i.Token.Val
@@ -75,6 +84,74 @@ func (i *IntegerLiteral) String() string {
 	return i.Token.Val
 }
 
+// StringLiteral value is the string with escape sequences resolved,
+// token value is the source text between quotes.
+type StringLiteral struct {
+	Token jlang.Token
+	Value string
+}
+
+func (sl *StringLiteral) expressionNode() {}
+
+func (sl *StringLiteral) TokenValue() string {
+	return sl.Token.Val
+}
+
+func (sl *StringLiteral) String() string {
+	return "\"" + sl.Token.Val + "\""
+}
+
+// InterpolatedString form will be "<string>${<expression>}<string>..."
+// Parts are string literals and interpolated expressions in source order.
+type InterpolatedString struct {
+	Token jlang.Token
+	Parts []Expression
+}
+
+func (is *InterpolatedString) expressionNode() {}
+
+func (is *InterpolatedString) TokenValue() string {
+	return is.Token.Val
+}
+
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("\"")
+	for _, part := range is.Parts {
+		if sl, ok := part.(*StringLiteral); ok {
+			out.WriteString(sl.Token.Val)
+		} else {
+			out.WriteString("${" + part.String() + "}")
+		}
+	}
+	out.WriteString("\"")
+
+	return out.String()
+}
+
+// Concatenation returns interpolated string as a chain of + infix expressions
+// starting from the leading string literal, so the result is always a string.
+// "a${x}b" will be (("a" + x) + "b") and "${x}" will be ("" + x).
+func (is *InterpolatedString) Concatenation() Expression {
+	exp := is.Parts[0]
+
+	for _, part := range is.Parts[1:] {
+		if sl, ok := part.(*StringLiteral); ok && sl.Value == "" {
+			continue
+		}
+
+		exp = &InfixExpression{
+			Token:           jlang.Token{Type: jlang.PLUS, Val: "+"},
+			Operator:        "+",
+			LeftExpression:  exp,
+			RightExpression: part,
+		}
+	}
+
+	return exp
+}
+
 type BooleanLiteral struct {
 	Token jlang.Token
 	Value bool
@@ -90,6 +167,127 @@ func (b *BooleanLiteral) String() string {
 	return b.Token.Val
 }
 
+// ArrayLiteral form will be [<expression>, <expression>, ...]
+type ArrayLiteral struct {
+	Token    jlang.Token
+	Elements []Expression
+}
+
+func (al *ArrayLiteral) expressionNode() {}
+
+func (al *ArrayLiteral) TokenValue() string {
+	return al.Token.Val
+}
+
+func (al *ArrayLiteral) String() string {
+	elements := []string{}
+	for _, e := range al.Elements {
+		elements = append(elements, e.String())
+	}
+
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// HashLiteral form will be {<expression>: <expression>, ...}
+// Pairs keep source order.
+type HashLiteral struct {
+	Token jlang.Token
+	Pairs []*HashPair
+}
+
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+func (hl *HashLiteral) expressionNode() {}
+
+func (hl *HashLiteral) TokenValue() string {
+	return hl.Token.Val
+}
+
+func (hl *HashLiteral) String() string {
+	pairs := []string{}
+	for _, p := range hl.Pairs {
+		pairs = append(pairs, p.Key.String()+": "+p.Value.String())
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// ComprehensionClause form will be <for> <pattern> <in> <expression> [<if> <condition>]
+// Condition is nil when clause has no filter.
+type ComprehensionClause struct {
+	Token     jlang.Token
+	Pattern   Pattern
+	Iterable  Expression
+	Condition Expression
+}
+
+func (cc *ComprehensionClause) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(" for ")
+	out.WriteString(cc.Pattern.String())
+	out.WriteString(" in ")
+	out.WriteString(cc.Iterable.String())
+	if cc.Condition != nil {
+		out.WriteString(" if ")
+		out.WriteString(cc.Condition.String())
+	}
+
+	return out.String()
+}
+
+// ListComprehension form will be [<expression> <comprehension clause>]
+type ListComprehension struct {
+	Token   jlang.Token
+	Element Expression
+	Clause  *ComprehensionClause
+}
+
+func (lc *ListComprehension) expressionNode() {}
+
+func (lc *ListComprehension) TokenValue() string {
+	return lc.Token.Val
+}
+
+func (lc *ListComprehension) String() string {
+	return "[" + lc.Element.String() + lc.Clause.String() + "]"
+}
+
+// HashComprehension form will be {<expression>: <expression> <comprehension clause>}
+type HashComprehension struct {
+	Token  jlang.Token
+	Key    Expression
+	Value  Expression
+	Clause *ComprehensionClause
+}
+
+func (hc *HashComprehension) expressionNode() {}
+
+func (hc *HashComprehension) TokenValue() string {
+	return hc.Token.Val
+}
+
+func (hc *HashComprehension) String() string {
+	return "{" + hc.Key.String() + ": " + hc.Value.String() + hc.Clause.String() + "}"
+}
+
+type NullLiteral struct {
+	Token jlang.Token
+}
+
+func (nl *NullLiteral) expressionNode() {}
+
+func (nl *NullLiteral) TokenValue() string {
+	return nl.Token.Val
+}
+
+func (nl *NullLiteral) String() string {
+	return nl.Token.Val
+}
+
 // PrefixExpression form will be <operator> <right expression>
 type PrefixExpression struct {
 	Token           jlang.Token
@@ -115,6 +313,31 @@ func (pe *PrefixExpression) String() string {
 	return out.String()
 }
 
+// PostfixExpression form will be <left expression> <operator>
+type PostfixExpression struct {
+	Token          jlang.Token
+	Operator       string
+	LeftExpression Expression
+}
+
+func (pe *PostfixExpression) expressionNode() {}
+
+func (pe *PostfixExpression) TokenValue() string {
+	return pe.Token.Val
+}
+
+func (pe *PostfixExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+
+	if pe.LeftExpression != nil {
+		out.WriteString(pe.LeftExpression.String())
+	}
+	out.WriteString(pe.Operator)
+	out.WriteString(")")
+	return out.String()
+}
+
 type InfixExpression struct {
 	Token           jlang.Token
 	Operator        string
@@ -143,10 +366,68 @@ func (ie *InfixExpression) String() string {
 	return out.String()
 }
 
+// PipeExpression form will be <expression> |> <expression>
+// x |> f(y) is a call of f with x inserted as the first argument, f(x, y).
+type PipeExpression struct {
+	Token           jlang.Token
+	LeftExpression  Expression
+	RightExpression Expression
+}
+
+func (pe *PipeExpression) expressionNode() {}
+
+func (pe *PipeExpression) TokenValue() string {
+	return pe.Token.Val
+}
+
+func (pe *PipeExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	if pe.LeftExpression != nil {
+		out.WriteString(pe.LeftExpression.String())
+	}
+	out.WriteString(" |> ")
+	if pe.RightExpression != nil {
+		out.WriteString(pe.RightExpression.String())
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// Call returns the call expression pipe desugars to.
+// Pipes on the left side are desugared too, so
+// data |> filter(isEven) |> map(double) will be map(filter(data, isEven), double).
+func (pe *PipeExpression) Call() *CallExpression {
+	left := pe.LeftExpression
+	if lp, ok := left.(*PipeExpression); ok {
+		left = lp.Call()
+	}
+
+	if call, ok := pe.RightExpression.(*CallExpression); ok {
+		return &CallExpression{
+			Token:    call.Token,
+			Function: call.Function,
+			Args:     append([]Expression{left}, call.Args...),
+		}
+	}
+
+	return &CallExpression{
+		Token:    pe.Token,
+		Function: pe.RightExpression,
+		Args:     []Expression{left},
+	}
+}
+
+// LetStatement form will be <let> <ident> <=> <expression>
+// or <let> <pattern> <=> <expression> for destructuring.
+// Pattern is nil when a single identifier is bound.
 type LetStatement struct {
-	Token jlang.Token
-	Ident *Identifier
-	Value Expression
+	Token   jlang.Token
+	Ident   *Identifier
+	Pattern Pattern
+	Value   Expression
 }
 
 func (ls *LetStatement) statementNode() {}
@@ -158,7 +439,11 @@ func (ls *LetStatement) TokenValue() string {
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(ls.Token.Val + " ")
-	out.WriteString(ls.Ident.Value + " = ")
+	if ls.Pattern != nil {
+		out.WriteString(ls.Pattern.String() + " = ")
+	} else {
+		out.WriteString(ls.Ident.Value + " = ")
+	}
 
 	if ls.Value != nil {
 		out.WriteString(ls.Value.String())
@@ -168,6 +453,154 @@ func (ls *LetStatement) String() string {
 	return out.String()
 }
 
+// ArrayPattern form will be [<pattern>, <pattern>, ..., ...<ident>]
+type ArrayPattern struct {
+	Token    jlang.Token
+	Elements []Pattern
+
+	// Rest collects remaining elements as a list, nil if pattern has no rest.
+	Rest *Identifier
+}
+
+func (ap *ArrayPattern) patternNode() {}
+
+func (ap *ArrayPattern) TokenValue() string {
+	return ap.Token.Val
+}
+
+func (ap *ArrayPattern) String() string {
+	elements := []string{}
+	for _, e := range ap.Elements {
+		elements = append(elements, e.String())
+	}
+
+	if ap.Rest != nil {
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// HashPattern form will be {<key>, <key>: <pattern>, ...}
+// Key without pattern binds the value to the identifier with same name.
+type HashPattern struct {
+	Token   jlang.Token
+	Entries []*HashPatternEntry
+}
+
+type HashPatternEntry struct {
+	Key   *Identifier
+	Value Pattern
+}
+
+func (hp *HashPattern) patternNode() {}
+
+func (hp *HashPattern) TokenValue() string {
+	return hp.Token.Val
+}
+
+func (hp *HashPattern) String() string {
+	entries := []string{}
+	for _, e := range hp.Entries {
+		if ident, ok := e.Value.(*Identifier); ok && ident.Value == e.Key.Value {
+			entries = append(entries, e.Key.String())
+		} else {
+			entries = append(entries, e.Key.String()+": "+e.Value.String())
+		}
+	}
+
+	return "{" + strings.Join(entries, ", ") + "}"
+}
+
+// LiteralPattern matches a value equal to the literal.
+type LiteralPattern struct {
+	Token jlang.Token
+	Value Expression
+}
+
+func (lp *LiteralPattern) patternNode() {}
+
+func (lp *LiteralPattern) TokenValue() string {
+	return lp.Token.Val
+}
+
+func (lp *LiteralPattern) String() string {
+	return lp.Value.String()
+}
+
+// VariantPattern form will be <variant>(<pattern>, ...) or <variant>
+// It matches tagged values of the enum variant and their payload.
+type VariantPattern struct {
+	Token jlang.Token
+	Name  *Identifier
+	Args  []Pattern
+}
+
+func (vp *VariantPattern) patternNode() {}
+
+func (vp *VariantPattern) TokenValue() string {
+	return vp.Token.Val
+}
+
+func (vp *VariantPattern) String() string {
+	if len(vp.Args) == 0 {
+		return vp.Name.String()
+	}
+
+	args := []string{}
+	for _, a := range vp.Args {
+		args = append(args, a.String())
+	}
+
+	return vp.Name.String() + "(" + strings.Join(args, ", ") + ")"
+}
+
+// WildcardPattern form will be <_>, it matches any value without binding.
+type WildcardPattern struct {
+	Token jlang.Token
+}
+
+func (wp *WildcardPattern) patternNode() {}
+
+func (wp *WildcardPattern) TokenValue() string {
+	return wp.Token.Val
+}
+
+func (wp *WildcardPattern) String() string {
+	return "_"
+}
+
+// PatternNames returns names bound by pattern in order of appearance.
+func PatternNames(pattern Pattern) []string {
+	switch p := pattern.(type) {
+	case *Identifier:
+		return []string{p.Value}
+	case *ArrayPattern:
+		names := []string{}
+		for _, e := range p.Elements {
+			names = append(names, PatternNames(e)...)
+		}
+		if p.Rest != nil {
+			names = append(names, p.Rest.Value)
+		}
+		return names
+	case *HashPattern:
+		names := []string{}
+		for _, e := range p.Entries {
+			names = append(names, PatternNames(e.Value)...)
+		}
+		return names
+	case *VariantPattern:
+		names := []string{}
+		for _, a := range p.Args {
+			names = append(names, PatternNames(a)...)
+		}
+		return names
+	}
+
+	return []string{}
+}
+
 type ReturnStatement struct {
 	Token       jlang.Token
 	ReturnValue Expression
@@ -191,6 +624,140 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
+// ThrowStatement form will be <throw> <expression>
+// It unwinds the stack to the nearest enclosing catch block.
+type ThrowStatement struct {
+	Token jlang.Token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode() {}
+
+func (ts *ThrowStatement) TokenValue() string {
+	return ts.Token.Val
+}
+
+func (ts *ThrowStatement) String() string {
+	return ts.Token.Val + " " + ts.Value.String() + ";"
+}
+
+// TryStatement form will be
+// <try> <blockstatement> [<catch> (<ident>) <blockstatement>] [<finally> <blockstatement>]
+// At least one of catch and finally exists. Finally block runs whether
+// try block completes, throws or returns.
+type TryStatement struct {
+	Token jlang.Token
+	Block *BlockStatement
+
+	// CatchParam and Catch are nil when there is no catch block
+	CatchParam *Identifier
+	Catch      *BlockStatement
+
+	// Finally is nil when there is no finally block
+	Finally *BlockStatement
+}
+
+func (ts *TryStatement) statementNode() {}
+
+func (ts *TryStatement) TokenValue() string {
+	return ts.Token.Val
+}
+
+func (ts *TryStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try{")
+	out.WriteString(ts.Block.String())
+	out.WriteString("}")
+
+	if ts.Catch != nil {
+		out.WriteString("catch(" + ts.CatchParam.String() + "){")
+		out.WriteString(ts.Catch.String())
+		out.WriteString("}")
+	}
+
+	if ts.Finally != nil {
+		out.WriteString("finally{")
+		out.WriteString(ts.Finally.String())
+		out.WriteString("}")
+	}
+
+	return out.String()
+}
+
+// ImportStatement form will be <import> <string> [<as> <ident>]
+// Imported module is bound to alias, or to the last element of path
+// without extension when alias is omitted.
+type ImportStatement struct {
+	Token jlang.Token
+	Path  *StringLiteral
+	Alias *Identifier
+}
+
+func (is *ImportStatement) statementNode() {}
+
+func (is *ImportStatement) TokenValue() string {
+	return is.Token.Val
+}
+
+func (is *ImportStatement) String() string {
+	if is.Alias == nil {
+		return is.Token.Val + " " + is.Path.String() + ";"
+	}
+
+	return is.Token.Val + " " + is.Path.String() + " as " + is.Alias.String() + ";"
+}
+
+// Name returns the name imported module is bound to.
+func (is *ImportStatement) Name() string {
+	if is.Alias != nil {
+		return is.Alias.Value
+	}
+
+	base := path.Base(is.Path.Value)
+	return strings.TrimSuffix(base, path.Ext(base))
+}
+
+// ExportStatement form will be <export> <statement>
+// Statement is a let, function, struct or enum declaration.
+type ExportStatement struct {
+	Token     jlang.Token
+	Statement Statement
+}
+
+func (es *ExportStatement) statementNode() {}
+
+func (es *ExportStatement) TokenValue() string {
+	return es.Token.Val
+}
+
+func (es *ExportStatement) String() string {
+	return es.Token.Val + " " + es.Statement.String()
+}
+
+// Names returns names exported by statement.
+func (es *ExportStatement) Names() []string {
+	switch stmt := es.Statement.(type) {
+	case *LetStatement:
+		if stmt.Pattern != nil {
+			return PatternNames(stmt.Pattern)
+		}
+		return []string{stmt.Ident.Value}
+	case *FunctionStatement:
+		return []string{stmt.Name.Value}
+	case *StructStatement:
+		return []string{stmt.Name.Value}
+	case *EnumStatement:
+		names := []string{stmt.Name.Value}
+		for _, v := range stmt.Variants {
+			names = append(names, v.Name.Value)
+		}
+		return names
+	}
+
+	return []string{}
+}
+
 type ExpressionStatement struct {
 	Token      jlang.Token
 	Expression Expression
@@ -253,10 +820,85 @@ func (ie *IFExpression) String() string {
 	return out.String()
 }
 
+// <match> <subject> { <pattern> [<if> <guard>] => <body>, ... }
+// Value of match expression is the value of the first arm whose pattern
+// matches the subject and whose guard holds.
+type MatchExpression struct {
+	Token   jlang.Token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+type MatchArm struct {
+	Token   jlang.Token
+	Pattern Pattern
+
+	// Guard is nil when arm has no guard
+	Guard Expression
+	Body  *BlockStatement
+}
+
+func (me *MatchExpression) expressionNode() {}
+
+func (me *MatchExpression) TokenValue() string {
+	return me.Token.Val
+}
+
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("match")
+	out.WriteString("(")
+	out.WriteString(me.Subject.String())
+	out.WriteString(")")
+	out.WriteString("{")
+
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		out.WriteString(" if " + ma.Guard.String())
+	}
+	out.WriteString(" => ")
+	out.WriteString("{")
+	out.WriteString(ma.Body.String())
+	out.WriteString("}")
+
+	return out.String()
+}
+
 type FunctionExpression struct {
 	Token jlang.Token
 	Args  []*Identifier
 	Body  *BlockStatement
+
+	// Default values of parameters keyed by parameter name.
+	// Parameters with default value always come after required parameters.
+	Defaults map[string]Expression
+
+	// Rest parameter collects remaining positional arguments as a list.
+	// Rest is nil when function does not have rest parameter.
+	Rest *Identifier
+
+	// Name of function, filled by function declaration or let binding.
+	// It is used to name the function in error messages and stack traces.
+	// Anonymous function has empty name.
+	Name string
+
+	// Generator is true when function is declared with fn* or contains yield.
+	// Calling a generator returns an iterator instead of running the body.
+	Generator bool
 }
 
 func (f *FunctionExpression) TokenValue() string {
@@ -267,10 +909,11 @@ func (f *FunctionExpression) String() string {
 	var out bytes.Buffer
 
 	out.WriteString("fn")
-	out.WriteString("(")
-	for _, arg := range f.Args {
-		out.WriteString(arg.String())
+	if f.Generator {
+		out.WriteString("*")
 	}
+	out.WriteString("(")
+	out.WriteString(f.parameters())
 	out.WriteString(")")
 	out.WriteString("{")
 	out.WriteString(f.Body.String())
@@ -279,10 +922,459 @@ func (f *FunctionExpression) String() string {
 	return out.String()
 }
 
+// parameters returns parameter list of function such as "x, y = 10, ...rest"
+func (f *FunctionExpression) parameters() string {
+	params := []string{}
+	for _, a := range f.Args {
+		if d, ok := f.Defaults[a.Value]; ok {
+			params = append(params, a.String()+" = "+d.String())
+		} else {
+			params = append(params, a.String())
+		}
+	}
+
+	if f.Rest != nil {
+		params = append(params, "..."+f.Rest.String())
+	}
+
+	return strings.Join(params, ", ")
+}
+
+// Arity returns the minimum and maximum number of positional arguments of function.
+// Maximum is -1 when function has rest parameter.
+func (f *FunctionExpression) Arity() (int, int) {
+	min := 0
+	for _, a := range f.Args {
+		if _, ok := f.Defaults[a.Value]; !ok {
+			min++
+		}
+	}
+
+	if f.Rest != nil {
+		return min, -1
+	}
+
+	return min, len(f.Args)
+}
+
+// CheckArgs checks whether function can be called with given number of
+// positional arguments and given keyword argument names.
+// Error explains what the function expected.
+func (f *FunctionExpression) CheckArgs(positional int, keywords []string) error {
+	name := f.Name
+	if name == "" {
+		name = "<anonymous>"
+	}
+
+	min, max := f.Arity()
+	if max != -1 && positional > max {
+		return fmt.Errorf("%s() takes %s, got %d",
+			name, describeArity(min, max), positional)
+	}
+
+	bound := make(map[string]bool)
+	for i, a := range f.Args {
+		if i < positional {
+			bound[a.Value] = true
+		}
+	}
+
+	for _, k := range keywords {
+		if !f.hasParameter(k) {
+			return fmt.Errorf("%s() got an unexpected keyword argument %s", name, k)
+		}
+
+		if bound[k] {
+			return fmt.Errorf("%s() got multiple values for argument %s", name, k)
+		}
+
+		bound[k] = true
+	}
+
+	for _, a := range f.Args {
+		if _, ok := f.Defaults[a.Value]; ok || bound[a.Value] {
+			continue
+		}
+
+		return fmt.Errorf("%s() missing argument %s, takes %s",
+			name, a.Value, describeArity(min, max))
+	}
+
+	return nil
+}
+
+func (f *FunctionExpression) hasParameter(name string) bool {
+	for _, a := range f.Args {
+		if a.Value == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func describeArity(min, max int) string {
+	switch {
+	case max == -1:
+		return fmt.Sprintf("at least %d %s", min, pluralArgument(min))
+	case min == max:
+		return fmt.Sprintf("%d %s", min, pluralArgument(min))
+	default:
+		return fmt.Sprintf("%d to %d arguments", min, max)
+	}
+}
+
+func pluralArgument(n int) string {
+	if n == 1 {
+		return "argument"
+	}
+
+	return "arguments"
+}
+
+// FunctionStatement form will be <fn> <name> <parameters> <blockstatement>
+// Function statements are hoisted within their scope, so functions declared
+// in same block can call each other regardless of declaration order.
+type FunctionStatement struct {
+	Token    jlang.Token
+	Name     *Identifier
+	Function *FunctionExpression
+}
+
+func (fs *FunctionStatement) statementNode() {}
+
+func (fs *FunctionStatement) TokenValue() string {
+	return fs.Token.Val
+}
+
+func (fs *FunctionStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("fn")
+	if fs.Function.Generator {
+		out.WriteString("*")
+	}
+	out.WriteString(" ")
+	out.WriteString(fs.Name.Value)
+	out.WriteString("(")
+	out.WriteString(fs.Function.parameters())
+	out.WriteString(")")
+	out.WriteString("{")
+	out.WriteString(fs.Function.Body.String())
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// StructStatement form will be <struct> <name> { <ident>, <ident>, ... }
+// It declares a constructor with the struct name taking fields as parameters.
+type StructStatement struct {
+	Token  jlang.Token
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+func (ss *StructStatement) statementNode() {}
+
+func (ss *StructStatement) TokenValue() string {
+	return ss.Token.Val
+}
+
+func (ss *StructStatement) String() string {
+	fields := []string{}
+	for _, f := range ss.Fields {
+		fields = append(fields, f.String())
+	}
+
+	return "struct " + ss.Name.String() + " { " + strings.Join(fields, ", ") + " }"
+}
+
+// HasField returns whether struct declares field with given name.
+func (ss *StructStatement) HasField(name string) bool {
+	for _, f := range ss.Fields {
+		if f.Value == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Constructor returns the signature of struct constructor.
+// Every field is a required parameter, so Point(1, 2) and Point(y: 2, x: 1)
+// are checked the same way as function calls.
+func (ss *StructStatement) Constructor() *FunctionExpression {
+	return &FunctionExpression{
+		Token:    ss.Token,
+		Name:     ss.Name.Value,
+		Args:     ss.Fields,
+		Defaults: map[string]Expression{},
+		Body:     &BlockStatement{Token: ss.Token, Statements: []Statement{}},
+	}
+}
+
+// EnumStatement form will be <enum> <name> { <variant>, <variant>(<ident>, ...), ... }
+// Each variant declares a constructor of tagged values with its payload fields.
+type EnumStatement struct {
+	Token    jlang.Token
+	Name     *Identifier
+	Variants []*EnumVariant
+}
+
+type EnumVariant struct {
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+func (es *EnumStatement) statementNode() {}
+
+func (es *EnumStatement) TokenValue() string {
+	return es.Token.Val
+}
+
+func (es *EnumStatement) String() string {
+	variants := []string{}
+	for _, v := range es.Variants {
+		variants = append(variants, v.String())
+	}
+
+	return "enum " + es.Name.String() + " { " + strings.Join(variants, ", ") + " }"
+}
+
+// Variant returns variant with given name, nil if enum does not have it.
+func (es *EnumStatement) Variant(name string) *EnumVariant {
+	for _, v := range es.Variants {
+		if v.Name.Value == name {
+			return v
+		}
+	}
+
+	return nil
+}
+
+func (ev *EnumVariant) String() string {
+	if len(ev.Fields) == 0 {
+		return ev.Name.String()
+	}
+
+	fields := []string{}
+	for _, f := range ev.Fields {
+		fields = append(fields, f.String())
+	}
+
+	return ev.Name.String() + "(" + strings.Join(fields, ", ") + ")"
+}
+
+// Constructor returns the signature of variant constructor.
+// Every payload field is a required parameter.
+func (ev *EnumVariant) Constructor() *FunctionExpression {
+	return &FunctionExpression{
+		Token:    ev.Name.Token,
+		Name:     ev.Name.Value,
+		Args:     ev.Fields,
+		Defaults: map[string]Expression{},
+		Body:     &BlockStatement{Token: ev.Name.Token, Statements: []Statement{}},
+	}
+}
+
+// SelectorExpression form will be <expression>.<ident> or <expression>?.<ident>
+// Method call obj.method(x) is a call expression whose function is a selector.
+type SelectorExpression struct {
+	Token    jlang.Token
+	Object   Expression
+	Selector *Identifier
+
+	// Optional selector evaluates to null without selecting when object is null,
+	// short-circuiting the rest of the chain up to enclosing ChainExpression.
+	Optional bool
+}
+
+func (se *SelectorExpression) expressionNode() {}
+
+func (se *SelectorExpression) TokenValue() string {
+	return se.Token.Val
+}
+
+func (se *SelectorExpression) String() string {
+	if se.Optional {
+		return se.Object.String() + "?." + se.Selector.String()
+	}
+
+	return se.Object.String() + "." + se.Selector.String()
+}
+
+// ChainExpression form will be (<chain>) where chain is selectors, index, slice and
+// call expressions with an optional link, such as (a?.b).
+// Parentheses end the chain, null short-circuited inside is not short-circuited
+// by selectors outside: a?.b.c is null when a is null while (a?.b).c fails.
+type ChainExpression struct {
+	Token jlang.Token
+	Chain Expression
+}
+
+func (ce *ChainExpression) expressionNode() {}
+
+func (ce *ChainExpression) TokenValue() string {
+	return ce.Token.Val
+}
+
+func (ce *ChainExpression) String() string {
+	return "(" + ce.Chain.String() + ")"
+}
+
+// IsOptionalChain returns whether expression is a chain of selectors, index, slice
+// and call expressions with an optional link not ended by parentheses.
+func IsOptionalChain(exp Expression) bool {
+	for {
+		switch e := exp.(type) {
+		case *SelectorExpression:
+			if e.Optional {
+				return true
+			}
+			exp = e.Object
+		case *IndexExpression:
+			if e.Optional {
+				return true
+			}
+			exp = e.Left
+		case *SliceExpression:
+			if e.Optional {
+				return true
+			}
+			exp = e.Left
+		case *CallExpression:
+			if e.Optional {
+				return true
+			}
+			exp = e.Function
+		default:
+			return false
+		}
+	}
+}
+
+// YieldExpression form will be <yield> [<expression>]
+// It suspends the enclosing generator and produces the value to the iterator.
+// Value is nil for bare yield.
+type YieldExpression struct {
+	Token jlang.Token
+	Value Expression
+}
+
+func (ye *YieldExpression) expressionNode() {}
+
+func (ye *YieldExpression) TokenValue() string {
+	return ye.Token.Val
+}
+
+func (ye *YieldExpression) String() string {
+	if ye.Value == nil {
+		return "yield"
+	}
+
+	return "yield " + ye.Value.String()
+}
+
+// RangeExpression form will be <expression>..<expression> or <expression>..=<expression>
+// It produces integers from start up to end lazily, end included when inclusive.
+type RangeExpression struct {
+	Token     jlang.Token
+	Start     Expression
+	End       Expression
+	Inclusive bool
+}
+
+func (re *RangeExpression) expressionNode() {}
+
+func (re *RangeExpression) TokenValue() string {
+	return re.Token.Val
+}
+
+func (re *RangeExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	if re.Start != nil {
+		out.WriteString(re.Start.String())
+	}
+	out.WriteString(re.Token.Val)
+	if re.End != nil {
+		out.WriteString(re.End.String())
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// IndexExpression form will be <expression>[<expression>] or <expression>?.[<expression>]
+type IndexExpression struct {
+	Token jlang.Token
+	Left  Expression
+	Index Expression
+
+	// Optional index evaluates to null when left is null
+	Optional bool
+}
+
+func (ie *IndexExpression) expressionNode() {}
+
+func (ie *IndexExpression) TokenValue() string {
+	return ie.Token.Val
+}
+
+func (ie *IndexExpression) String() string {
+	if ie.Optional {
+		return ie.Left.String() + "?.[" + ie.Index.String() + "]"
+	}
+
+	return ie.Left.String() + "[" + ie.Index.String() + "]"
+}
+
+// SliceExpression form will be <expression>[<low>:<high>]
+// Low and High are nil when omitted. Negative indices count from the end.
+type SliceExpression struct {
+	Token jlang.Token
+	Left  Expression
+	Low   Expression
+	High  Expression
+
+	// Optional slice evaluates to null when left is null
+	Optional bool
+}
+
+func (se *SliceExpression) expressionNode() {}
+
+func (se *SliceExpression) TokenValue() string {
+	return se.Token.Val
+}
+
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(se.Left.String())
+	if se.Optional {
+		out.WriteString("?.")
+	}
+	out.WriteString("[")
+	if se.Low != nil {
+		out.WriteString(se.Low.String())
+	}
+	out.WriteString(":")
+	if se.High != nil {
+		out.WriteString(se.High.String())
+	}
+	out.WriteString("]")
+
+	return out.String()
+}
+
 type CallExpression struct {
 	Token    jlang.Token
 	Function Expression
 	Args     []Expression
+
+	// Optional call f?.(x) evaluates to null without calling when function is null
+	Optional bool
 }
 
 func (c *CallExpression) TokenValue() string {
@@ -298,6 +1390,9 @@ func (c *CallExpression) String() string {
 	}
 
 	out.WriteString(c.Function.String())
+	if c.Optional {
+		out.WriteString("?.")
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(args, ", "))
 	out.WriteString(")")
@@ -305,6 +1400,41 @@ func (c *CallExpression) String() string {
 	return out.String()
 }
 
+// SpreadExpression form will be <...> <expression>
+// It expands a list into positional arguments at call sites.
+type SpreadExpression struct {
+	Token jlang.Token
+	Value Expression
+}
+
+func (se *SpreadExpression) expressionNode() {}
+
+func (se *SpreadExpression) TokenValue() string {
+	return se.Token.Val
+}
+
+func (se *SpreadExpression) String() string {
+	return "..." + se.Value.String()
+}
+
+// KeywordArgument form will be <ident> <:> <expression>
+// It binds an argument to the parameter with same name at call sites.
+type KeywordArgument struct {
+	Token jlang.Token
+	Name  *Identifier
+	Value Expression
+}
+
+func (ka *KeywordArgument) expressionNode() {}
+
+func (ka *KeywordArgument) TokenValue() string {
+	return ka.Token.Val
+}
+
+func (ka *KeywordArgument) String() string {
+	return ka.Name.String() + ": " + ka.Value.String()
+}
+
 func (c *CallExpression) expressionNode() {
 	panic("implement me")
 }
@@ -96,3 +96,54 @@ func TestCallExpression_String(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%q", callExp.String())
 	}
 }
+
+func TestFunctionExpression_CheckArgs(t *testing.T) {
+	ident := func(name string) *Identifier {
+		return &Identifier{Value: name, Token: jlang.Token{Val: name, Type: jlang.IDENT}}
+	}
+
+	fn := &FunctionExpression{
+		Name: "add",
+		Args: []*Identifier{ident("x"), ident("y")},
+		Defaults: map[string]Expression{
+			"y": &IntegerLiteral{Value: 10, Token: jlang.Token{Val: "10", Type: jlang.INT}},
+		},
+	}
+
+	variadic := &FunctionExpression{
+		Args: []*Identifier{ident("first")},
+		Rest: ident("rest"),
+	}
+
+	tests := []struct {
+		fn            *FunctionExpression
+		positional    int
+		keywords      []string
+		expectedError string
+	}{
+		{fn, 1, nil, ""},
+		{fn, 2, nil, ""},
+		{fn, 0, []string{"y", "x"}, ""},
+		{fn, 3, nil, "add() takes 1 to 2 arguments, got 3"},
+		{fn, 0, nil, "add() missing argument x, takes 1 to 2 arguments"},
+		{fn, 1, []string{"x"}, "add() got multiple values for argument x"},
+		{fn, 1, []string{"z"}, "add() got an unexpected keyword argument z"},
+		{variadic, 5, nil, ""},
+		{variadic, 0, nil, "<anonymous>() missing argument first, takes at least 1 argument"},
+	}
+
+	for _, tt := range tests {
+		err := tt.fn.CheckArgs(tt.positional, tt.keywords)
+
+		if tt.expectedError == "" {
+			if err != nil {
+				t.Errorf("unexpected error. got=%q", err)
+			}
+			continue
+		}
+
+		if err == nil || err.Error() != tt.expectedError {
+			t.Errorf("error wrong. expected=%q, got=%v", tt.expectedError, err)
+		}
+	}
+}
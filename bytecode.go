@@ -0,0 +1,422 @@
+package jlang
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strconv"
+)
+
+// Layout of .jbc file. Integers are big endian.
+//
+//	magic           "JBC\x00"
+//	version         uint16
+//	constants       uint32 count, each <type byte> <payload>
+//	functions       uint32 count, each <name> <params uint32> <locals uint32> <code> <handlers> <lines>
+//	instructions    <code> <handlers> of top level code
+//	symbols         uint32 count, each <name> <index uint32>
+//	debug           <source name> <lines> of top level code
+//	checksum        uint32 CRC-32 (IEEE) of everything above
+//
+// Strings and code are prefixed with their uint32 length,
+// lines are uint32 count followed by <offset uint32> <line uint32> pairs and
+// handlers are uint32 count followed by <start uint32> <end uint32> <target uint32>.
+const (
+	BytecodeMagic   = "JBC\x00"
+	BytecodeVersion = 1
+
+	// Extension of compiled files
+	BytecodeExtension = ".jbc"
+)
+
+var ErrTruncatedBytecode = errors.New("bytecode is truncated")
+
+type ConstantType byte
+
+const (
+	IntegerConstantType ConstantType = iota + 1
+	StringConstantType
+	BooleanConstantType
+	NullConstantType
+	FunctionConstantType
+)
+
+// Constant is a value in constant pool of bytecode.
+type Constant interface {
+	Type() ConstantType
+	String() string
+}
+
+type IntegerConstant int64
+
+func (c IntegerConstant) Type() ConstantType { return IntegerConstantType }
+func (c IntegerConstant) String() string     { return strconv.FormatInt(int64(c), 10) }
+
+type StringConstant string
+
+func (c StringConstant) Type() ConstantType { return StringConstantType }
+func (c StringConstant) String() string     { return strconv.Quote(string(c)) }
+
+type BooleanConstant bool
+
+func (c BooleanConstant) Type() ConstantType { return BooleanConstantType }
+func (c BooleanConstant) String() string     { return strconv.FormatBool(bool(c)) }
+
+type NullConstant struct{}
+
+func (c NullConstant) Type() ConstantType { return NullConstantType }
+func (c NullConstant) String() string     { return "null" }
+
+// FunctionConstant is index of compiled function in Bytecode.Functions.
+type FunctionConstant int
+
+func (c FunctionConstant) Type() ConstantType { return FunctionConstantType }
+func (c FunctionConstant) String() string     { return "fn#" + strconv.Itoa(int(c)) }
+
+// Line maps instructions from Offset up to offset of next line to source Line,
+// which starts from 1.
+type Line struct {
+	Offset int
+	Line   int
+}
+
+// Handler is an entry of exception table. Exception thrown while running instructions
+// from Start up to End, including calls made by them, unwinds stack to locals of the
+// frame, pushes the exception and jumps to Target. Handlers are searched in order,
+// so handlers of inner try statements come first. Finally blocks are handlers too
+// which throw the exception again at their end.
+type Handler struct {
+	Start  int
+	End    int
+	Target int
+}
+
+type CompiledFunction struct {
+	Name         string
+	NumParams    int
+	NumLocals    int
+	Instructions Instructions
+	Handlers     []Handler
+	Lines        []Line
+}
+
+// Symbol is a global name and its index.
+type Symbol struct {
+	Name  string
+	Index int
+}
+
+// Bytecode is compiled program.
+type Bytecode struct {
+	Instructions Instructions
+	Handlers     []Handler
+	Constants    []Constant
+	Functions    []*CompiledFunction
+	Symbols      []Symbol
+
+	// Debug information, may be empty
+	Source string
+	Lines  []Line
+}
+
+// MarshalBinary encodes bytecode into .jbc format.
+func (b *Bytecode) MarshalBinary() ([]byte, error) {
+	e := &encoder{}
+
+	e.buf.WriteString(BytecodeMagic)
+	e.uint16(BytecodeVersion)
+
+	e.uint32(len(b.Constants))
+	for _, c := range b.Constants {
+		if err := e.constant(c); err != nil {
+			return nil, err
+		}
+	}
+
+	e.uint32(len(b.Functions))
+	for _, fn := range b.Functions {
+		e.string(fn.Name)
+		e.uint32(fn.NumParams)
+		e.uint32(fn.NumLocals)
+		e.bytes(fn.Instructions)
+		e.handlers(fn.Handlers)
+		e.lines(fn.Lines)
+	}
+
+	e.bytes(b.Instructions)
+	e.handlers(b.Handlers)
+
+	e.uint32(len(b.Symbols))
+	for _, s := range b.Symbols {
+		e.string(s.Name)
+		e.uint32(s.Index)
+	}
+
+	e.string(b.Source)
+	e.lines(b.Lines)
+
+	if e.err != nil {
+		return nil, e.err
+	}
+
+	e.buf.Write(binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE(e.buf.Bytes())))
+	return e.buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes bytecode in .jbc format.
+// Malformed data results in error. Unmarshaled bytecode should be verified before
+// it is run since instructions are not checked.
+func (b *Bytecode) UnmarshalBinary(data []byte) error {
+	if len(data) < len(BytecodeMagic)+2+4 || string(data[:len(BytecodeMagic)]) != BytecodeMagic {
+		return errors.New("not a bytecode file, magic number is missing")
+	}
+
+	body := data[:len(data)-4]
+	if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(data[len(data)-4:]) {
+		return errors.New("bytecode checksum mismatch")
+	}
+
+	d := &decoder{data: body, pos: len(BytecodeMagic)}
+
+	if version := d.uint16(); version != BytecodeVersion {
+		return fmt.Errorf("unsupported bytecode version %d, expected %d", version, BytecodeVersion)
+	}
+
+	decoded := &Bytecode{
+		Constants: []Constant{},
+		Functions: []*CompiledFunction{},
+		Symbols:   []Symbol{},
+	}
+
+	for i, n := 0, d.count(1); i < n; i++ {
+		decoded.Constants = append(decoded.Constants, d.constant())
+	}
+
+	for i, n := 0, d.count(4*5); i < n; i++ {
+		decoded.Functions = append(decoded.Functions, &CompiledFunction{
+			Name:         d.string(),
+			NumParams:    d.uint32(),
+			NumLocals:    d.uint32(),
+			Instructions: Instructions(d.bytes()),
+			Handlers:     d.handlers(),
+			Lines:        d.lines(),
+		})
+	}
+
+	decoded.Instructions = Instructions(d.bytes())
+	decoded.Handlers = d.handlers()
+
+	for i, n := 0, d.count(4*2); i < n; i++ {
+		decoded.Symbols = append(decoded.Symbols, Symbol{Name: d.string(), Index: d.uint32()})
+	}
+
+	decoded.Source = d.string()
+	decoded.Lines = d.lines()
+
+	if d.err != nil {
+		return d.err
+	}
+
+	if d.pos != len(d.data) {
+		return fmt.Errorf("unexpected %d bytes after bytecode", len(d.data)-d.pos)
+	}
+
+	*b = *decoded
+	return nil
+}
+
+type encoder struct {
+	buf bytes.Buffer
+	err error
+}
+
+func (e *encoder) uint16(v int) {
+	e.buf.Write(binary.BigEndian.AppendUint16(nil, uint16(v)))
+}
+
+func (e *encoder) uint32(v int) {
+	if v < 0 || uint64(v) > 0xFFFFFFFF {
+		if e.err == nil {
+			e.err = fmt.Errorf("%d does not fit in bytecode", v)
+		}
+		return
+	}
+
+	e.buf.Write(binary.BigEndian.AppendUint32(nil, uint32(v)))
+}
+
+func (e *encoder) bytes(v []byte) {
+	e.uint32(len(v))
+	e.buf.Write(v)
+}
+
+func (e *encoder) string(v string) {
+	e.bytes([]byte(v))
+}
+
+func (e *encoder) lines(lines []Line) {
+	e.uint32(len(lines))
+	for _, l := range lines {
+		e.uint32(l.Offset)
+		e.uint32(l.Line)
+	}
+}
+
+func (e *encoder) handlers(handlers []Handler) {
+	e.uint32(len(handlers))
+	for _, h := range handlers {
+		e.uint32(h.Start)
+		e.uint32(h.End)
+		e.uint32(h.Target)
+	}
+}
+
+func (e *encoder) constant(c Constant) error {
+	if c == nil {
+		return errors.New("constant is nil")
+	}
+
+	e.buf.WriteByte(byte(c.Type()))
+
+	switch c := c.(type) {
+	case IntegerConstant:
+		e.buf.Write(binary.BigEndian.AppendUint64(nil, uint64(c)))
+	case StringConstant:
+		e.string(string(c))
+	case BooleanConstant:
+		if c {
+			e.buf.WriteByte(1)
+		} else {
+			e.buf.WriteByte(0)
+		}
+	case NullConstant:
+	case FunctionConstant:
+		e.uint32(int(c))
+	default:
+		return fmt.Errorf("unknown constant %T", c)
+	}
+
+	return nil
+}
+
+// decoder reads big endian values. After first error every read returns
+// zero value and the error is kept in err.
+type decoder struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (d *decoder) read(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+
+	if n < 0 || n > len(d.data)-d.pos {
+		d.err = ErrTruncatedBytecode
+		return nil
+	}
+
+	v := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return v
+}
+
+func (d *decoder) byte() byte {
+	if v := d.read(1); v != nil {
+		return v[0]
+	}
+
+	return 0
+}
+
+func (d *decoder) uint16() int {
+	if v := d.read(2); v != nil {
+		return int(binary.BigEndian.Uint16(v))
+	}
+
+	return 0
+}
+
+func (d *decoder) uint32() int {
+	if v := d.read(4); v != nil {
+		return int(binary.BigEndian.Uint32(v))
+	}
+
+	return 0
+}
+
+// count reads number of entries each of which takes at least size bytes.
+// Counts larger than remaining data are rejected before anything is allocated.
+func (d *decoder) count(size int) int {
+	n := d.uint32()
+	if n > (len(d.data)-d.pos)/size {
+		if d.err == nil {
+			d.err = ErrTruncatedBytecode
+		}
+		return 0
+	}
+
+	return n
+}
+
+func (d *decoder) bytes() []byte {
+	v := d.read(d.uint32())
+	return append([]byte{}, v...)
+}
+
+func (d *decoder) string() string {
+	return string(d.read(d.uint32()))
+}
+
+func (d *decoder) lines() []Line {
+	lines := []Line{}
+	for i, n := 0, d.count(4*2); i < n; i++ {
+		lines = append(lines, Line{Offset: d.uint32(), Line: d.uint32()})
+	}
+
+	return lines
+}
+
+func (d *decoder) handlers() []Handler {
+	handlers := []Handler{}
+	for i, n := 0, d.count(4*3); i < n; i++ {
+		handlers = append(handlers, Handler{Start: d.uint32(), End: d.uint32(), Target: d.uint32()})
+	}
+
+	return handlers
+}
+
+func (d *decoder) constant() Constant {
+	switch t := ConstantType(d.byte()); t {
+	case IntegerConstantType:
+		if v := d.read(8); v != nil {
+			return IntegerConstant(int64(binary.BigEndian.Uint64(v)))
+		}
+	case StringConstantType:
+		return StringConstant(d.string())
+	case BooleanConstantType:
+		switch d.byte() {
+		case 0:
+			return BooleanConstant(false)
+		case 1:
+			return BooleanConstant(true)
+		default:
+			if d.err == nil {
+				d.err = errors.New("invalid boolean constant")
+			}
+		}
+	case NullConstantType:
+		return NullConstant{}
+	case FunctionConstantType:
+		return FunctionConstant(d.uint32())
+	default:
+		if d.err == nil {
+			d.err = fmt.Errorf("unknown constant type %d", t)
+		}
+	}
+
+	return nil
+}
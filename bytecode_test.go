@@ -0,0 +1,108 @@
+package jlang
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"reflect"
+	"testing"
+)
+
+func testBytecode() *Bytecode {
+	return &Bytecode{
+		Instructions: Instructions{byte(OpConstant), 0, 1},
+		Handlers:     []Handler{},
+		Constants: []Constant{
+			IntegerConstant(-42),
+			StringConstant("hello\n"),
+			BooleanConstant(true),
+			NullConstant{},
+			FunctionConstant(0),
+		},
+		Functions: []*CompiledFunction{
+			{
+				Name:         "add",
+				NumParams:    2,
+				NumLocals:    3,
+				Instructions: Instructions{byte(OpConstant), 0, 0},
+				Handlers:     []Handler{{Start: 0, End: 3, Target: 3}, {Start: 0, End: 6, Target: 9}},
+				Lines:        []Line{{Offset: 0, Line: 2}},
+			},
+		},
+		Symbols: []Symbol{{Name: "add", Index: 0}},
+		Source:  "main.j",
+		Lines:   []Line{{Offset: 0, Line: 1}},
+	}
+}
+
+// withChecksum replaces checksum of data so that only the body is malformed.
+func withChecksum(body []byte) []byte {
+	return binary.BigEndian.AppendUint32(append([]byte{}, body...), crc32.ChecksumIEEE(body))
+}
+
+func TestBytecode_MarshalBinary(t *testing.T) {
+	bytecode := testBytecode()
+
+	data, err := bytecode.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	decoded := &Bytecode{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(bytecode, decoded) {
+		t.Errorf("decoded bytecode wrong. expected=%+v, got=%+v", bytecode, decoded)
+	}
+
+	if _, err := (&Bytecode{Constants: []Constant{nil}}).MarshalBinary(); err == nil {
+		t.Errorf("expected error for nil constant. got none")
+	}
+}
+
+func TestBytecode_UnmarshalBinary(t *testing.T) {
+	data, err := testBytecode().MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	body := data[:len(data)-4]
+
+	version := append([]byte{}, body...)
+	version[5] = 2
+
+	constantType := append([]byte{}, body...)
+	constantType[10] = 99
+
+	tests := []struct {
+		input         []byte
+		expectedError string
+	}{
+		{[]byte{}, "not a bytecode file, magic number is missing"},
+		{[]byte("#!/usr/bin/env jlang\n"), "not a bytecode file, magic number is missing"},
+		{append(append([]byte{}, body...), 0, 0, 0, 0), "bytecode checksum mismatch"},
+		{withChecksum(version), "unsupported bytecode version 2, expected 1"},
+		{withChecksum(constantType), "unknown constant type 99"},
+		{withChecksum(append(append([]byte{}, body...), 0)), "unexpected 1 bytes after bytecode"},
+		{withChecksum(append([]byte(BytecodeMagic), 0, 1, 0xFF, 0xFF, 0xFF, 0xFF)), "bytecode is truncated"},
+	}
+
+	for _, tt := range tests {
+		err := (&Bytecode{}).UnmarshalBinary(tt.input)
+		if err == nil {
+			t.Errorf("expected error %q for %q. got none", tt.expectedError, tt.input)
+			continue
+		}
+
+		if err.Error() != tt.expectedError {
+			t.Errorf("error wrong. expected=%q, got=%q", tt.expectedError, err.Error())
+		}
+	}
+
+	// every truncated body must fail without panic
+	for i := len(BytecodeMagic) + 2; i < len(body); i++ {
+		if err := (&Bytecode{}).UnmarshalBinary(withChecksum(body[:i])); err == nil {
+			t.Errorf("expected error for body truncated at %d. got none", i)
+		}
+	}
+}
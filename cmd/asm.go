@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/junbeomlee/jlang"
+)
+
+// asm assembles assembly source into .jbc file.
+func asm(args []string) error {
+	flags := flag.NewFlagSet("asm", flag.ExitOnError)
+	out := flags.String("o", "", "output file, defaults to source file with "+jlang.BytecodeExtension+" extension")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: jlang asm [-o file.jbc] file.jasm\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	file := flags.Arg(0)
+	if *out == "" {
+		*out = strings.TrimSuffix(file, filepath.Ext(file)) + jlang.BytecodeExtension
+	}
+
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	bytecode, err := jlang.AssembleBytecode(string(src))
+	if err != nil {
+		return fmt.Errorf("%s: %s", file, err)
+	}
+	bytecode.Source = file
+
+	// hand written bytecode is checked before it is written out rather than when it is run
+	if err := jlang.Verify(bytecode); err != nil {
+		return fmt.Errorf("%s: %s", file, err)
+	}
+
+	data, err := bytecode.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(*out, data, 0644)
+}
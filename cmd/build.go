@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/junbeomlee/jlang"
+	"github.com/junbeomlee/jlang/module"
+)
+
+// build compiles source file into .jbc file which runs without the source.
+// Files with imports are not built since .jbc file holds a single module.
+func build(args []string) error {
+	flags := flag.NewFlagSet("build", flag.ExitOnError)
+	out := flags.String("o", "", "output file, defaults to source file with "+jlang.BytecodeExtension+" extension")
+	path := flags.String("path", "", "list of directories separated by "+string(os.PathListSeparator)+" to search imports in")
+	opt := addOptimizeFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: jlang build [-O0 | -O1 | -O2] [-o file.jbc] file.j\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	file := flags.Arg(0)
+	if *out == "" {
+		*out = strings.TrimSuffix(file, filepath.Ext(file)) + jlang.BytecodeExtension
+	}
+
+	m, err := module.NewLoader(searchPaths(*path)).Load(file)
+	if err != nil {
+		return err
+	}
+
+	if len(m.Imports) != 0 {
+		return fmt.Errorf("%s: cannot build file with imports, %s file does not include imported modules",
+			m.Path, jlang.BytecodeExtension)
+	}
+
+	c, err := opt.compiler()
+	if err != nil {
+		return err
+	}
+
+	data, err := compile(c)(m)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(*out, data, 0644)
+}
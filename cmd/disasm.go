@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/junbeomlee/jlang"
+	"github.com/junbeomlee/jlang/module"
+)
+
+// disasm prints compiled instructions of source or .jbc file.
+func disasm(args []string) error {
+	flags := flag.NewFlagSet("disasm", flag.ExitOnError)
+	path := flags.String("path", "", "list of directories separated by "+string(os.PathListSeparator)+" to search imports in")
+	opt := addOptimizeFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: jlang disasm [-O0 | -O1 | -O2] file.j | file.jbc\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	file := flags.Arg(0)
+
+	var data []byte
+	if filepath.Ext(file) == jlang.BytecodeExtension {
+		read, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		data = read
+	} else {
+		m, err := module.NewLoader(searchPaths(*path)).Load(file)
+		if err != nil {
+			return err
+		}
+
+		c, err := opt.compiler()
+		if err != nil {
+			return err
+		}
+
+		if data, err = compile(c)(m); err != nil {
+			return err
+		}
+	}
+
+	// bytecode is not verified so that malformed files can be inspected
+	bytecode := &jlang.Bytecode{}
+	if err := bytecode.UnmarshalBinary(data); err != nil {
+		return fmt.Errorf("%s: %s", file, err)
+	}
+
+	// source of .jbc file is shown when it is still around
+	source := ""
+	if src, err := os.ReadFile(bytecode.Source); err == nil {
+		source = string(src)
+	}
+
+	return jlang.Disassemble(os.Stdout, bytecode, source)
+}
@@ -1,14 +1,57 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"os/user"
+	"path/filepath"
 
+	"github.com/junbeomlee/jlang"
+	"github.com/junbeomlee/jlang/module"
 	"github.com/junbeomlee/jlang/repl"
 )
 
+var (
+	noCache = flag.Bool("no-cache", false, "compile every module without reading or writing the cache")
+	path    = flag.String("path", "", "list of directories separated by "+string(os.PathListSeparator)+" to search imports in")
+	opt     = addOptimizeFlags(flag.CommandLine)
+)
+
+// commands run with arguments following command name
+var commands = map[string]func(args []string) error{
+	"build":  build,
+	"asm":    asm,
+	"disasm": disasm,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: jlang [flags] [file.j | file.jbc]\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "       jlang build [-O0 | -O1 | -O2] [-o file.jbc] file.j\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "       jlang asm [-o file.jbc] file.jasm\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "       jlang disasm [-O0 | -O1 | -O2] file.j | file.jbc\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() > 0 {
+		if err := run(flag.Arg(0)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	user, err := user.Current()
 	if err != nil {
@@ -20,3 +63,62 @@ func main() {
 	fmt.Printf("Feel free to type in commands\n")
 	repl.Start(os.Stdin, os.Stdout)
 }
+
+// run loads file with its imports, compiles them and runs the compiled file.
+// Compiled .jbc file is run as is.
+func run(file string) error {
+	if filepath.Ext(file) == jlang.BytecodeExtension {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		if _, err := jlang.LoadBytecode(data); err != nil {
+			return fmt.Errorf("%s: %s", file, err)
+		}
+
+		jlang.NewVM(data)
+		return nil
+	}
+
+	m, err := module.NewLoader(searchPaths(*path)).Load(file)
+	if err != nil {
+		return err
+	}
+
+	c, err := opt.compiler()
+	if err != nil {
+		return err
+	}
+
+	// cache is only an optimization, modules are compiled without it
+	// when there is no cache directory
+	var cache *module.Cache
+	if !*noCache && !opt.dumps() {
+		if dir, err := module.DefaultCacheDir(); err == nil {
+			cache = module.NewCache(dir, c.String())
+		}
+	}
+
+	compiled, err := module.Compile(m, cache, compile(c))
+	if err != nil {
+		return err
+	}
+
+	if cache != nil {
+		for _, msg := range cache.Warnings() {
+			fmt.Fprintln(os.Stderr, "warning: "+msg)
+		}
+	}
+
+	jlang.NewVM(compiled[m.Path])
+	return nil
+}
+
+func searchPaths(path string) []string {
+	if path == "" {
+		return []string{}
+	}
+
+	return filepath.SplitList(path)
+}
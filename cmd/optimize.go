@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/junbeomlee/jlang/module"
+	"github.com/junbeomlee/jlang/optimizer"
+)
+
+// defaultLevel is optimization level without -O flag.
+const defaultLevel = 1
+
+// optimizeFlags select optimization passes of commands compiling source files.
+type optimizeFlags struct {
+	level     int
+	enable    *string
+	disable   *string
+	dumpAfter *string
+}
+
+func addOptimizeFlags(flags *flag.FlagSet) *optimizeFlags {
+	f := &optimizeFlags{level: defaultLevel}
+
+	for level := 0; level <= optimizer.MaxLevel; level++ {
+		flags.Var(levelFlag{level: &f.level, value: level}, fmt.Sprintf("O%d", level),
+			fmt.Sprintf("run optimization passes of level %d", level))
+	}
+
+	f.enable = flags.String("enable", "", "comma separated passes to run regardless of optimization level")
+	f.disable = flags.String("disable", "", "comma separated passes to skip regardless of optimization level")
+	f.dumpAfter = flags.String("dump-after", "", "print IR to stderr after pass, parse or compile")
+
+	return f
+}
+
+// compiler returns compiler running passes selected by flags.
+func (f *optimizeFlags) compiler() (*optimizer.Compiler, error) {
+	c := optimizer.NewCompiler(f.level)
+
+	for _, name := range passNames(*f.enable) {
+		if err := c.Enable(name); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range passNames(*f.disable) {
+		if err := c.Disable(name); err != nil {
+			return nil, err
+		}
+	}
+
+	if *f.dumpAfter != "" {
+		if err := c.DumpAfter(*f.dumpAfter, os.Stderr); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// dumps returns whether IR is printed, which needs every module to be compiled.
+func (f *optimizeFlags) dumps() bool {
+	return *f.dumpAfter != ""
+}
+
+func passNames(list string) []string {
+	names := []string{}
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// levelFlag is a -O<n> flag, the last one given sets the level.
+type levelFlag struct {
+	level *int
+	value int
+}
+
+func (f levelFlag) String() string {
+	return strconv.FormatBool(f.level != nil && *f.level == f.value)
+}
+
+func (f levelFlag) Set(s string) error {
+	set, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+
+	if set {
+		*f.level = f.value
+	}
+	return nil
+}
+
+func (f levelFlag) IsBoolFlag() bool {
+	return true
+}
+
+// compile returns function compiling modules with c.
+func compile(c *optimizer.Compiler) func(m *module.Module) ([]byte, error) {
+	return func(m *module.Module) ([]byte, error) {
+		bytecode, err := c.Compile(m.Program)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", m.Path, err)
+		}
+		bytecode.Source = m.Path
+
+		return bytecode.MarshalBinary()
+	}
+}
@@ -1,5 +1,11 @@
 package jlang
 
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
 type Instructions []byte
 
 type Opcode byte
@@ -8,6 +14,52 @@ const (
 
 	// Constant opcode represents constant value
 	OpConstant Opcode = iota
+
+	// Pop discards top of stack
+	OpPop
+
+	// Arithmetic and comparison opcodes pop two operands and push the result
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpEqual
+	OpNotEqual
+	OpGreaterThan
+
+	// Prefix opcodes replace top of stack
+	OpMinus
+	OpBang
+
+	OpTrue
+	OpFalse
+	OpNull
+
+	// Jump opcodes have absolute offset of jump target as operand
+	OpJump
+	OpJumpNotTruthy
+
+	OpGetGlobal
+	OpSetGlobal
+	OpGetLocal
+	OpSetLocal
+
+	// Array and hash opcodes have number of elements to pop as operand
+	OpArray
+	OpHash
+	OpIndex
+
+	// Call opcode has number of arguments as operand
+	OpCall
+	OpReturnValue
+	OpReturn
+
+	// Wide prefix doubles widths of operands of following instruction
+	// so that indexes which overflow the usual widths can be encoded
+	OpWide
+
+	// Superinstruction of OpGetLocal OpConstant OpAdd with local and constant index as operands
+	OpAddLocalConstant
 )
 
 // Description for opcode
@@ -27,8 +79,249 @@ var opDictionary map[Opcode]OpcodeDesc
 func init() {
 	opDictionary = make(map[Opcode]OpcodeDesc)
 	opDictionary[OpConstant] = OpcodeDesc{"OpConstant", []int{2}}
+	opDictionary[OpPop] = OpcodeDesc{"OpPop", []int{}}
+	opDictionary[OpAdd] = OpcodeDesc{"OpAdd", []int{}}
+	opDictionary[OpSub] = OpcodeDesc{"OpSub", []int{}}
+	opDictionary[OpMul] = OpcodeDesc{"OpMul", []int{}}
+	opDictionary[OpDiv] = OpcodeDesc{"OpDiv", []int{}}
+	opDictionary[OpEqual] = OpcodeDesc{"OpEqual", []int{}}
+	opDictionary[OpNotEqual] = OpcodeDesc{"OpNotEqual", []int{}}
+	opDictionary[OpGreaterThan] = OpcodeDesc{"OpGreaterThan", []int{}}
+	opDictionary[OpMinus] = OpcodeDesc{"OpMinus", []int{}}
+	opDictionary[OpBang] = OpcodeDesc{"OpBang", []int{}}
+	opDictionary[OpTrue] = OpcodeDesc{"OpTrue", []int{}}
+	opDictionary[OpFalse] = OpcodeDesc{"OpFalse", []int{}}
+	opDictionary[OpNull] = OpcodeDesc{"OpNull", []int{}}
+	opDictionary[OpJump] = OpcodeDesc{"OpJump", []int{2}}
+	opDictionary[OpJumpNotTruthy] = OpcodeDesc{"OpJumpNotTruthy", []int{2}}
+	opDictionary[OpGetGlobal] = OpcodeDesc{"OpGetGlobal", []int{2}}
+	opDictionary[OpSetGlobal] = OpcodeDesc{"OpSetGlobal", []int{2}}
+	opDictionary[OpGetLocal] = OpcodeDesc{"OpGetLocal", []int{1}}
+	opDictionary[OpSetLocal] = OpcodeDesc{"OpSetLocal", []int{1}}
+	opDictionary[OpArray] = OpcodeDesc{"OpArray", []int{2}}
+	opDictionary[OpHash] = OpcodeDesc{"OpHash", []int{2}}
+	opDictionary[OpIndex] = OpcodeDesc{"OpIndex", []int{}}
+	opDictionary[OpCall] = OpcodeDesc{"OpCall", []int{1}}
+	opDictionary[OpReturnValue] = OpcodeDesc{"OpReturnValue", []int{}}
+	opDictionary[OpReturn] = OpcodeDesc{"OpReturn", []int{}}
+	opDictionary[OpWide] = OpcodeDesc{"OpWide", []int{}}
+	opDictionary[OpAddLocalConstant] = OpcodeDesc{"OpAddLocalConstant", []int{1, 2}}
+}
+
+// Lookup returns description of opcode.
+func Lookup(op byte) (OpcodeDesc, error) {
+	desc, ok := opDictionary[Opcode(op)]
+	if !ok {
+		return OpcodeDesc{}, fmt.Errorf("opcode %d undefined", op)
+	}
+
+	return desc, nil
+}
+
+// Make encodes instruction of opcode with operands.
+// Instruction is prefixed with OpWide and encoded with wide operands when
+// an operand does not fit in its width.
+// It returns empty instruction if opcode is undefined or an operand does not fit
+// even in wide operand.
+func Make(op Opcode, operands ...int) []byte {
+	desc, ok := opDictionary[op]
+	if !ok {
+		return []byte{}
+	}
+
+	if fits(desc, operands) {
+		return encode(op, desc, operands)
+	}
+
+	wide := Wide(desc)
+	if len(wide.OperandsWidth) == 0 || !fits(wide, operands) {
+		return []byte{}
+	}
+
+	return append([]byte{byte(OpWide)}, encode(op, wide, operands)...)
+}
+
+// Wide returns description of opcode with widths of operands doubled, which is how
+// instruction is encoded after OpWide prefix.
+func Wide(desc OpcodeDesc) OpcodeDesc {
+	widths := make([]int, len(desc.OperandsWidth))
+	for i, w := range desc.OperandsWidth {
+		widths[i] = w * 2
+	}
+
+	return OpcodeDesc{desc.Name, widths}
+}
+
+// fits returns whether operands fit in their widths.
+func fits(desc OpcodeDesc, operands []int) bool {
+	for i, o := range operands {
+		if i >= len(desc.OperandsWidth) {
+			break
+		}
+
+		if o < 0 || uint64(o) >= 1<<(8*uint(desc.OperandsWidth[i])) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func encode(op Opcode, desc OpcodeDesc, operands []int) []byte {
+	instruction := make([]byte, 1+operandsWidth(desc))
+	instruction[0] = byte(op)
+
+	offset := 1
+	for i, o := range operands {
+		if i >= len(desc.OperandsWidth) {
+			break
+		}
+
+		switch desc.OperandsWidth[i] {
+		case 1:
+			instruction[offset] = byte(o)
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 4:
+			binary.BigEndian.PutUint32(instruction[offset:], uint32(o))
+		}
+		offset += desc.OperandsWidth[i]
+	}
+
+	return instruction
+}
+
+// ReadOperands decodes operands of instruction described by desc from ins,
+// which starts right after the opcode. It returns operands and number of bytes read.
+// ins must be long enough to hold the operands.
+func ReadOperands(desc OpcodeDesc, ins Instructions) ([]int, int) {
+	operands := make([]int, len(desc.OperandsWidth))
+	offset := 0
+
+	for i, width := range desc.OperandsWidth {
+		switch width {
+		case 1:
+			operands[i] = int(ins[offset])
+		case 2:
+			operands[i] = int(binary.BigEndian.Uint16(ins[offset:]))
+		case 4:
+			operands[i] = int(binary.BigEndian.Uint32(ins[offset:]))
+		}
+		offset += width
+	}
+
+	return operands, offset
 }
 
-//func Encode() []byte{
-//
-//}
+// Instruction is an instruction decoded from Instructions.
+type Instruction struct {
+
+	// Offset of instruction, which is offset of OpWide prefix for wide instruction
+	Offset int
+
+	Opcode   Opcode
+	Desc     OpcodeDesc
+	Operands []int
+	Wide     bool
+
+	// Offset of following instruction
+	Next int
+}
+
+// ReadInstruction decodes instruction at offset of ins.
+// It returns error if opcode is undefined or operands are truncated.
+func ReadInstruction(ins Instructions, offset int) (*Instruction, error) {
+	decoded := &Instruction{Offset: offset}
+
+	pos := offset
+	if Opcode(ins[pos]) == OpWide {
+		decoded.Wide = true
+		pos++
+
+		if pos >= len(ins) {
+			return nil, fmt.Errorf("OpWide is not followed by instruction")
+		}
+	}
+
+	desc, err := Lookup(ins[pos])
+	if err != nil {
+		return nil, err
+	}
+
+	if decoded.Wide {
+		if len(desc.OperandsWidth) == 0 {
+			return nil, fmt.Errorf("OpWide is followed by %s which has no operands", desc.Name)
+		}
+		desc = Wide(desc)
+	}
+
+	if pos+1+operandsWidth(desc) > len(ins) {
+		return nil, fmt.Errorf("operands of %s are truncated", desc.Name)
+	}
+
+	operands, read := ReadOperands(desc, ins[pos+1:])
+
+	decoded.Opcode = Opcode(ins[pos])
+	decoded.Desc = desc
+	decoded.Operands = operands
+	decoded.Next = pos + 1 + read
+
+	return decoded, nil
+}
+
+// operandsWidth returns total width of operands of instruction described by desc.
+func operandsWidth(desc OpcodeDesc) int {
+	width := 0
+	for _, w := range desc.OperandsWidth {
+		width += w
+	}
+
+	return width
+}
+
+// String returns instructions in form of <offset> <opcode name> <operands> per line.
+// Wide instruction is shown with OpWide before opcode name.
+func (ins Instructions) String() string {
+	var out bytes.Buffer
+
+	for i := 0; i < len(ins); {
+		decoded, err := ReadInstruction(ins, i)
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+
+			// operands can only be truncated at the end, otherwise skip the byte and go on
+			if _, err := Lookup(ins[i]); err == nil && Opcode(ins[i]) != OpWide {
+				break
+			}
+			i++
+			continue
+		}
+
+		fmt.Fprintf(&out, "%04d %s\n", i, decoded)
+		i = decoded.Next
+	}
+
+	return out.String()
+}
+
+// String returns opcode name and operands of instruction.
+func (ins *Instruction) String() string {
+	out := ins.Desc.Name
+	if ins.Wide {
+		out = OpWide.String() + " " + out
+	}
+
+	for _, o := range ins.Operands {
+		out += fmt.Sprintf(" %d", o)
+	}
+
+	return out
+}
+
+func (op Opcode) String() string {
+	if desc, ok := opDictionary[op]; ok {
+		return desc.Name
+	}
+
+	return fmt.Sprintf("Opcode(%d)", byte(op))
+}
@@ -8,3 +8,156 @@ import (
 func Test_ad(t *testing.T) {
 	fmt.Println(OpConstant)
 }
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		operands []int
+		expected []byte
+	}{
+		{OpConstant, []int{65534}, []byte{byte(OpConstant), 255, 254}},
+		{OpGetLocal, []int{255}, []byte{byte(OpGetLocal), 255}},
+		{OpAdd, []int{}, []byte{byte(OpAdd)}},
+		{Opcode(255), []int{}, []byte{}},
+	}
+
+	for _, tt := range tests {
+		instruction := Make(tt.op, tt.operands...)
+
+		if string(instruction) != string(tt.expected) {
+			t.Errorf("instruction wrong. expected=%v, got=%v", tt.expected, instruction)
+		}
+	}
+}
+
+func TestReadOperands(t *testing.T) {
+	tests := []struct {
+		op        Opcode
+		operands  []int
+		bytesRead int
+	}{
+		{OpConstant, []int{65535}, 2},
+		{OpGetLocal, []int{255}, 1},
+		{OpPop, []int{}, 0},
+	}
+
+	for _, tt := range tests {
+		instruction := Make(tt.op, tt.operands...)
+
+		desc, err := Lookup(byte(tt.op))
+		if err != nil {
+			t.Fatalf("definition not found: %q", err)
+		}
+
+		operands, n := ReadOperands(desc, instruction[1:])
+		if n != tt.bytesRead {
+			t.Fatalf("n wrong. expected=%d, got=%d", tt.bytesRead, n)
+		}
+
+		for i, want := range tt.operands {
+			if operands[i] != want {
+				t.Errorf("operand wrong. expected=%d, got=%d", want, operands[i])
+			}
+		}
+	}
+}
+
+func TestInstructions_String(t *testing.T) {
+	instructions := []Instructions{
+		Make(OpAdd),
+		Make(OpGetLocal, 1),
+		Make(OpConstant, 2),
+		Make(OpConstant, 65535),
+		{255},
+		{byte(OpJump), 0},
+	}
+
+	expected := `0000 OpAdd
+0001 OpGetLocal 1
+0003 OpConstant 2
+0006 OpConstant 65535
+ERROR: opcode 255 undefined
+ERROR: operands of OpJump are truncated
+`
+
+	concatted := Instructions{}
+	for _, ins := range instructions {
+		concatted = append(concatted, ins...)
+	}
+
+	if concatted.String() != expected {
+		t.Errorf("instructions wrongly formatted.\nwant=%q\ngot=%q", expected, concatted.String())
+	}
+}
+
+func TestMake_Wide(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		operands []int
+		expected []byte
+	}{
+		{OpConstant, []int{65535}, []byte{byte(OpConstant), 255, 255}},
+		{OpConstant, []int{65536}, []byte{byte(OpWide), byte(OpConstant), 0, 1, 0, 0}},
+		{OpGetLocal, []int{256}, []byte{byte(OpWide), byte(OpGetLocal), 1, 0}},
+		{OpCall, []int{300}, []byte{byte(OpWide), byte(OpCall), 1, 44}},
+		{OpGetLocal, []int{65536}, []byte{}},
+		{OpConstant, []int{-1}, []byte{}},
+	}
+
+	for _, tt := range tests {
+		instruction := Make(tt.op, tt.operands...)
+
+		if string(instruction) != string(tt.expected) {
+			t.Errorf("instruction of %s %v wrong. expected=%v, got=%v", tt.op, tt.operands, tt.expected, instruction)
+			continue
+		}
+
+		if len(instruction) == 0 {
+			continue
+		}
+
+		decoded, err := ReadInstruction(instruction, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if decoded.Opcode != tt.op || decoded.Operands[0] != tt.operands[0] || decoded.Next != len(instruction) {
+			t.Errorf("decoded instruction wrong. got=%+v", decoded)
+		}
+	}
+}
+
+func TestReadInstruction_Errors(t *testing.T) {
+	tests := []struct {
+		input         Instructions
+		expectedError string
+	}{
+		{Instructions{byte(OpWide)}, "OpWide is not followed by instruction"},
+		{Instructions{byte(OpWide), byte(OpAdd)}, "OpWide is followed by OpAdd which has no operands"},
+		{Instructions{byte(OpWide), byte(OpWide), byte(OpConstant)}, "OpWide is followed by OpWide which has no operands"},
+		{Instructions{byte(OpWide), byte(OpConstant), 0, 0, 0}, "operands of OpConstant are truncated"},
+		{Instructions{byte(OpWide), 255}, "opcode 255 undefined"},
+	}
+
+	for _, tt := range tests {
+		_, err := ReadInstruction(tt.input, 0)
+		if err == nil {
+			t.Errorf("expected error %q for %v. got none", tt.expectedError, tt.input)
+			continue
+		}
+
+		if err.Error() != tt.expectedError {
+			t.Errorf("error wrong. expected=%q, got=%q", tt.expectedError, err.Error())
+		}
+	}
+
+	ins := concat(Make(OpConstant, 70000), Make(OpGetLocal, 1000), Make(OpPop))
+	expected := `0000 OpWide OpConstant 70000
+0006 OpWide OpGetLocal 1000
+0010 OpPop
+`
+
+	if ins.String() != expected {
+		t.Errorf("instructions wrongly formatted.\nwant=%q\ngot=%q", expected, ins.String())
+	}
+}
@@ -1,5 +1,8 @@
 package jlang
 
+// Version of compiler. Bytecode compiled by different versions is not compatible.
+const CompilerVersion = "0.1.0"
+
 type Compiler struct {
 }
 
@@ -7,6 +10,17 @@ func NewCompiler() *Compiler {
 	return &Compiler{}
 }
 
-func (c Compiler) Compile() []byte {
-	return []byte{}
+func (c Compiler) Bytecode() *Bytecode {
+	return &Bytecode{
+		Instructions: Instructions{},
+		Constants:    []Constant{},
+		Functions:    []*CompiledFunction{},
+		Symbols:      []Symbol{},
+		Lines:        []Line{},
+	}
+}
+
+// Compile returns bytecode encoded in .jbc format.
+func (c Compiler) Compile() ([]byte, error) {
+	return c.Bytecode().MarshalBinary()
 }
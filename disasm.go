@@ -0,0 +1,183 @@
+package jlang
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Disassemble writes instructions of top level code and every compiled function of
+// bytecode with offsets, decoded operands and jump target labels. Operands are followed
+// by the constant, global or jump target they refer to, and each source line of source
+// is written before the instructions compiled from it. Exception table follows the
+// instructions of its code with handler offsets labeled like jump targets. Source may be empty.
+func Disassemble(w io.Writer, b *Bytecode, source string) error {
+	d := &disassembler{
+		w:        w,
+		bytecode: b,
+		source:   strings.Split(source, "\n"),
+		globals:  make(map[int]string),
+	}
+
+	if source == "" {
+		d.source = []string{}
+	}
+
+	for _, s := range b.Symbols {
+		d.globals[s.Index] = s.Name
+	}
+
+	d.printf("top level code:\n")
+	d.unit(b.Instructions, b.Handlers, b.Lines)
+
+	for i, fn := range b.Functions {
+		d.printf("\nfunction #%d %s (%d params, %d locals):\n", i, fn.Name, fn.NumParams, fn.NumLocals)
+		d.unit(fn.Instructions, fn.Handlers, fn.Lines)
+	}
+
+	return d.err
+}
+
+type disassembler struct {
+	w        io.Writer
+	bytecode *Bytecode
+	source   []string
+	globals  map[int]string
+	err      error
+}
+
+func (d *disassembler) printf(format string, args ...interface{}) {
+	if d.err != nil {
+		return
+	}
+
+	_, d.err = fmt.Fprintf(d.w, format, args...)
+}
+
+func (d *disassembler) unit(ins Instructions, handlers []Handler, lines []Line) {
+	decoded := []*Instruction{}
+
+	// malformed instruction ends the listing
+	var decodeErr error
+	errOffset := 0
+
+	for offset := 0; offset < len(ins); {
+		instruction, err := ReadInstruction(ins, offset)
+		if err != nil {
+			decodeErr, errOffset = err, offset
+			break
+		}
+
+		decoded = append(decoded, instruction)
+		offset = instruction.Next
+	}
+
+	labels := jumpLabels(decoded, handlers)
+	sourceLines := make(map[int]int)
+	for _, l := range lines {
+		sourceLines[l.Offset] = l.Line
+	}
+
+	lastLine := 0
+	for _, instruction := range decoded {
+		if line, ok := sourceLines[instruction.Offset]; ok && line != lastLine {
+			d.sourceLine(line)
+			lastLine = line
+		}
+
+		if label, ok := labels[instruction.Offset]; ok {
+			d.printf("%s:\n", label)
+		}
+
+		text := instruction.String()
+		if comment := d.comment(instruction, labels); comment != "" {
+			text = fmt.Sprintf("%-28s ; %s", text, comment)
+		}
+
+		d.printf("  %04d %s\n", instruction.Offset, text)
+	}
+
+	if decodeErr != nil {
+		d.printf("  %04d ERROR: %s\n", errOffset, decodeErr)
+		return
+	}
+
+	if label, ok := labels[len(ins)]; ok {
+		d.printf("%s:\n", label)
+	}
+
+	if len(handlers) > 0 {
+		d.printf("handlers:\n")
+	}
+
+	for _, h := range handlers {
+		d.printf("  %s..%s => %s\n", labels[h.Start], labels[h.End], labels[h.Target])
+	}
+}
+
+func (d *disassembler) sourceLine(line int) {
+	if line < 1 || line > len(d.source) {
+		d.printf("%4d |\n", line)
+		return
+	}
+
+	d.printf("%4d | %s\n", line, strings.TrimRight(d.source[line-1], " \t\r"))
+}
+
+// comment returns what operand of instruction refers to.
+func (d *disassembler) comment(ins *Instruction, labels map[int]string) string {
+	switch ins.Opcode {
+	case OpConstant, OpAddLocalConstant:
+		index := ins.Operands[len(ins.Operands)-1]
+		if index >= len(d.bytecode.Constants) {
+			return "undefined constant"
+		}
+
+		c := d.bytecode.Constants[index]
+		if fn, ok := c.(FunctionConstant); ok && int(fn) < len(d.bytecode.Functions) {
+			return fmt.Sprintf("%s %s", fn, d.bytecode.Functions[fn].Name)
+		}
+		return c.String()
+	case OpGetGlobal, OpSetGlobal:
+		return d.globals[ins.Operands[0]]
+	case OpJump, OpJumpNotTruthy:
+		return labels[ins.Operands[0]]
+	}
+
+	return ""
+}
+
+// jumpLabels names jump targets and handler offsets L0, L1, ... in order of their offsets.
+func jumpLabels(instructions []*Instruction, handlers []Handler) map[int]string {
+	targets := []int{}
+	seen := make(map[int]bool)
+
+	add := func(target int) {
+		if !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+
+	for _, ins := range instructions {
+		if ins.Opcode == OpJump || ins.Opcode == OpJumpNotTruthy {
+			add(ins.Operands[0])
+		}
+	}
+
+	for _, h := range handlers {
+		add(h.Start)
+		add(h.End)
+		add(h.Target)
+	}
+
+	sort.Ints(targets)
+
+	labels := make(map[int]string)
+	for i, target := range targets {
+		labels[target] = fmt.Sprintf("L%d", i)
+	}
+
+	return labels
+}
@@ -0,0 +1,93 @@
+package jlang
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDisassemble(t *testing.T) {
+	source := `let x = if (true) { double(10) } else { null };
+fn double(n) {
+	n + n
+}`
+
+	bytecode := &Bytecode{
+		Instructions: concat(
+			Make(OpTrue),
+			Make(OpJumpNotTruthy, 15),
+			Make(OpConstant, 1),
+			Make(OpConstant, 0),
+			Make(OpCall, 1),
+			Make(OpJump, 16),
+			Make(OpNull),
+			Make(OpSetGlobal, 0),
+			Make(OpConstant, 70000),
+		),
+		Constants: []Constant{IntegerConstant(10), FunctionConstant(0)},
+		Functions: []*CompiledFunction{{
+			Name:         "double",
+			NumParams:    1,
+			NumLocals:    1,
+			Instructions: concat(Make(OpGetLocal, 0), Make(OpGetLocal, 0), Make(OpAdd), Make(OpReturnValue)),
+			Handlers:     []Handler{{Start: 2, End: 5, Target: 5}},
+			Lines:        []Line{{Offset: 0, Line: 3}},
+		}},
+		Symbols: []Symbol{{Name: "x", Index: 0}},
+		Lines:   []Line{{Offset: 0, Line: 1}, {Offset: 16, Line: 1}, {Offset: 19, Line: 9}},
+	}
+
+	expected := `top level code:
+   1 | let x = if (true) { double(10) } else { null };
+  0000 OpTrue
+  0001 OpJumpNotTruthy 15           ; L0
+  0004 OpConstant 1                 ; fn#0 double
+  0007 OpConstant 0                 ; 10
+  0010 OpCall 1
+  0012 OpJump 16                    ; L1
+L0:
+  0015 OpNull
+L1:
+  0016 OpSetGlobal 0                ; x
+   9 |
+  0019 OpWide OpConstant 70000      ; undefined constant
+
+function #0 double (1 params, 1 locals):
+   3 | 	n + n
+  0000 OpGetLocal 0
+L0:
+  0002 OpGetLocal 0
+  0004 OpAdd
+L1:
+  0005 OpReturnValue
+handlers:
+  L0..L1 => L1
+`
+
+	var out bytes.Buffer
+	if err := Disassemble(&out, bytecode, source); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if out.String() != expected {
+		t.Errorf("disassembly wrong.\nwant=%s\ngot=%s", expected, out.String())
+	}
+}
+
+func TestDisassemble_Malformed(t *testing.T) {
+	bytecode := &Bytecode{Instructions: concat(Make(OpJump, 4), Make(OpPop), Instructions{byte(OpConstant), 1})}
+
+	expected := `top level code:
+  0000 OpJump 4                     ; L0
+  0003 OpPop
+  0004 ERROR: operands of OpConstant are truncated
+`
+
+	var out bytes.Buffer
+	if err := Disassemble(&out, bytecode, ""); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if out.String() != expected {
+		t.Errorf("disassembly wrong.\nwant=%s\ngot=%s", expected, out.String())
+	}
+}
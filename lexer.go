@@ -2,6 +2,7 @@ package jlang
 
 import (
 	"bytes"
+	"strings"
 )
 
 const eof = 0
@@ -13,6 +14,12 @@ type Lexer struct {
 	pos      int
 	line     int
 	tokench  chan Token
+
+	// brace depth of each open string interpolation, innermost last
+	interpolations []int
+
+	// offsets of opening quotes of open strings, innermost last
+	quotes []int
 }
 
 func New(input string) *Lexer {
@@ -146,6 +153,9 @@ func lexInput(l *Lexer) stateFn {
 		if l.peek() == '=' {
 			l.next()
 			l.emit(EQ)
+		} else if l.peek() == '>' {
+			l.next()
+			l.emit(ARROW)
 		} else {
 			l.emit(ASSIGN)
 		}
@@ -165,6 +175,24 @@ func lexInput(l *Lexer) stateFn {
 	case ch == '*':
 		l.emit(ASTERISK)
 
+	case ch == '|':
+		if l.peek() == '>' {
+			l.next()
+			l.emit(PIPE)
+		} else {
+			l.emit(ILLEGAL)
+		}
+	case ch == '?':
+		switch l.peek() {
+		case '.':
+			l.next()
+			l.emit(QUESTION_DOT)
+		case '?':
+			l.next()
+			l.emit(NULLISH)
+		default:
+			l.emit(QUESTION)
+		}
 	case ch == '<':
 		l.emit(LT)
 	case ch == '>':
@@ -178,12 +206,50 @@ func lexInput(l *Lexer) stateFn {
 		l.emit(LPAREN)
 	case ch == ',':
 		l.emit(COMMA)
+	case ch == ':':
+		l.emit(COLON)
+	case ch == '.':
+		switch {
+		case strings.HasPrefix(l.input[l.pos:], ".."):
+			l.next()
+			l.next()
+			l.emit(ELLIPSIS)
+		case strings.HasPrefix(l.input[l.pos:], ".="):
+			l.next()
+			l.next()
+			l.emit(RANGE_INCLUSIVE)
+		case l.peek() == '.':
+			l.next()
+			l.emit(RANGE)
+		default:
+			l.emit(DOT)
+		}
 	case ch == '+':
 		l.emit(PLUS)
 	case ch == '{':
+		if n := len(l.interpolations); n > 0 {
+			l.interpolations[n-1]++
+		}
 		l.emit(LBRACE)
 	case ch == '}':
+		if n := len(l.interpolations); n > 0 {
+			// closing brace of interpolation resumes the string
+			if l.interpolations[n-1] == 0 {
+				l.interpolations = l.interpolations[:n-1]
+				l.ignore()
+				return lexString
+			}
+			l.interpolations[n-1]--
+		}
 		l.emit(RBRACE)
+	case ch == '"':
+		l.quotes = append(l.quotes, l.start)
+		l.ignore()
+		return lexString
+	case ch == '[':
+		l.emit(LBRACKET)
+	case ch == ']':
+		l.emit(RBRACKET)
 	case isSpace(ch):
 		return lexSpace
 	case isDigit(ch):
@@ -264,3 +330,44 @@ func lexNumber(l *Lexer) stateFn {
 	l.emit(INT)
 	return lexInput
 }
+
+// lexString scans a string segment until the closing quote or "${".
+// Quotes and interpolation delimiters are not included in the token value
+// and escape sequences are left for the parser.
+func lexString(l *Lexer) stateFn {
+	for {
+		switch ch := l.next(); {
+		case ch == '\\':
+			if l.next() == eof {
+				l.unterminated()
+				return lexInput
+			}
+		case ch == '"':
+			l.backup()
+			l.emit(STRING)
+			l.next()
+			l.ignore()
+			l.quotes = l.quotes[:len(l.quotes)-1]
+			return lexInput
+		case ch == '$' && l.peek() == '{':
+			l.backup()
+			l.emit(STRING_PART)
+			l.next()
+			l.next()
+			l.ignore()
+			l.interpolations = append(l.interpolations, 0)
+			return lexInput
+		case ch == eof:
+			l.unterminated()
+			return lexInput
+		}
+	}
+}
+
+// unterminated emits the rest of unterminated string from its opening quote
+// as ILLEGAL token, so that it is reported at the quote.
+func (l *Lexer) unterminated() {
+	quote := l.quotes[len(l.quotes)-1]
+	l.tokench <- Token{ILLEGAL, l.input[quote:l.pos], quote, l.pos, strings.Count(l.input[:quote], "\n")}
+	l.start = l.pos
+}
@@ -217,11 +217,101 @@ func TestLexer_NextToken3(t *testing.T) {
 	}
 
 	if l.line != 18 {
-		t.Fatalf("num of line was wrong. expected=%q, got=%q",
+		t.Fatalf("num of line was wrong. expected=%d, got=%d",
 			18, l.line)
 	}
 }
 
+func TestLexer_NextToken_Arguments(t *testing.T) {
+	input := `f(...list, y: 2)`
+
+	tests := []struct {
+		expectedType  TokenType
+		expectedValue string
+	}{
+		{IDENT, "f"},
+		{LPAREN, "("},
+		{ELLIPSIS, "..."},
+		{IDENT, "list"},
+		{COMMA, ","},
+		{IDENT, "y"},
+		{COLON, ":"},
+		{INT, "2"},
+		{RPAREN, ")"},
+		{EOF, ""},
+	}
+
+	l := New(input)
+	for _, test := range tests {
+		token := l.NextToken()
+		assert.Equal(t, test.expectedType, token.Type)
+		assert.Equal(t, test.expectedValue, token.Val)
+	}
+}
+
+func TestLexer_NextToken_String(t *testing.T) {
+	input := `"hi \"${name}\"" + "n: ${ {a: 1}["a"] }!"`
+
+	tests := []struct {
+		expectedType  TokenType
+		expectedValue string
+	}{
+		{STRING_PART, `hi \"`},
+		{IDENT, "name"},
+		{STRING, `\"`},
+		{PLUS, "+"},
+		{STRING_PART, "n: "},
+		{LBRACE, "{"},
+		{IDENT, "a"},
+		{COLON, ":"},
+		{INT, "1"},
+		{RBRACE, "}"},
+		{LBRACKET, "["},
+		{STRING, "a"},
+		{RBRACKET, "]"},
+		{STRING, "!"},
+		{EOF, ""},
+	}
+
+	l := New(input)
+	for _, test := range tests {
+		token := l.NextToken()
+		assert.Equal(t, test.expectedType, token.Type)
+		assert.Equal(t, test.expectedValue, token.Val)
+	}
+}
+
+func TestLexer_NextToken_Dots(t *testing.T) {
+	input := `a.b 0..1 0..=1 [...xs]`
+
+	tests := []struct {
+		expectedType  TokenType
+		expectedValue string
+	}{
+		{IDENT, "a"},
+		{DOT, "."},
+		{IDENT, "b"},
+		{INT, "0"},
+		{RANGE, ".."},
+		{INT, "1"},
+		{INT, "0"},
+		{RANGE_INCLUSIVE, "..="},
+		{INT, "1"},
+		{LBRACKET, "["},
+		{ELLIPSIS, "..."},
+		{IDENT, "xs"},
+		{RBRACKET, "]"},
+		{EOF, ""},
+	}
+
+	l := New(input)
+	for _, test := range tests {
+		token := l.NextToken()
+		assert.Equal(t, test.expectedType, token.Type)
+		assert.Equal(t, test.expectedValue, token.Val)
+	}
+}
+
 func TestLexer_NextToken4(t *testing.T) {
 	input := `2*3+5`
 
@@ -0,0 +1,201 @@
+package module
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/junbeomlee/jlang"
+)
+
+// MaxCacheAge is how long an entry is kept in cache without being used.
+// Every change of a source file results in a new entry, old entries are
+// removed when a new one is stored.
+const MaxCacheAge = 30 * 24 * time.Hour
+
+// Cache stores compiled bytecode of modules on disk.
+// Entries are keyed by compiler version and configuration, module path and source
+// and keys of its imports, so a change in any transitive import results in a different
+// key. Path is part of the key since bytecode records its source file.
+type Cache struct {
+	dir string
+
+	// compiler configuration, such as optimization passes, bytecode depends on
+	config string
+
+	// keys of modules already computed
+	keys map[*Module]string
+
+	// entries which could not be stored
+	warnings []string
+}
+
+func NewCache(dir string, config string) *Cache {
+	return &Cache{
+		dir:    dir,
+		config: config,
+		keys:   make(map[*Module]string),
+	}
+}
+
+// DefaultCacheDir returns directory of cache under user cache directory.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "jlang"), nil
+}
+
+// Key returns cache key of module.
+func (c *Cache) Key(m *Module) string {
+	if key, ok := c.keys[m]; ok {
+		return key
+	}
+
+	names := []string{}
+	for name := range m.Imports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "jlang %s %s\n%s %s\n", jlang.CompilerVersion, c.config, m.Path, m.Hash)
+	for _, name := range names {
+		fmt.Fprintf(h, "%s %s\n", name, c.Key(m.Imports[name]))
+	}
+
+	key := fmt.Sprintf("%x", h.Sum(nil))
+	c.keys[m] = key
+	return key
+}
+
+// Get returns cached bytecode of module, false if module is not cached.
+// Entries are loaded like .jbc files, and corrupt, truncated or outdated
+// entries are removed so that module is compiled again.
+func (c *Cache) Get(m *Module) ([]byte, bool) {
+	data, err := os.ReadFile(c.path(m))
+	if err != nil {
+		return nil, false
+	}
+
+	if _, err := jlang.LoadBytecode(data); err != nil {
+		os.Remove(c.path(m))
+		return nil, false
+	}
+
+	// entries in use are never pruned
+	now := time.Now()
+	os.Chtimes(c.path(m), now, now)
+
+	return data, true
+}
+
+// Put stores bytecode of module and removes entries unused for MaxCacheAge.
+func (c *Cache) Put(m *Module, data []byte) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+
+	// write to temporary file first so that concurrent runs never read
+	// a partially written entry
+	tmp, err := os.CreateTemp(c.dir, "tmp-")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), c.path(m)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	c.prune(time.Now().Add(-MaxCacheAge))
+	return nil
+}
+
+// Warnings returns entries which could not be stored. Cache is only an
+// optimization, so modules are compiled whether they are stored or not.
+func (c *Cache) Warnings() []string {
+	return c.warnings
+}
+
+// prune removes entries not used since before.
+func (c *Cache) prune(before time.Time) {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), jlang.BytecodeExtension) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err == nil && info.ModTime().Before(before) {
+			os.Remove(filepath.Join(c.dir, entry.Name()))
+		}
+	}
+}
+
+func (c *Cache) path(m *Module) string {
+	return filepath.Join(c.dir, c.Key(m)+jlang.BytecodeExtension)
+}
+
+// Compile compiles module and its transitive imports and returns bytecode
+// keyed by module path. Modules found in cache are not compiled again, and
+// modules which cannot be stored in cache are reported by its Warnings.
+// Cache may be nil to always compile.
+func Compile(m *Module, cache *Cache, compile func(*Module) ([]byte, error)) (map[string][]byte, error) {
+	compiled := make(map[string][]byte)
+	return compiled, compileAll(m, cache, compile, compiled)
+}
+
+func compileAll(m *Module, cache *Cache, compile func(*Module) ([]byte, error), compiled map[string][]byte) error {
+	if _, ok := compiled[m.Path]; ok {
+		return nil
+	}
+
+	for _, imported := range m.Imports {
+		if err := compileAll(imported, cache, compile, compiled); err != nil {
+			return err
+		}
+	}
+
+	if cache != nil {
+		if data, ok := cache.Get(m); ok {
+			compiled[m.Path] = data
+			return nil
+		}
+	}
+
+	data, err := compile(m)
+	if err != nil {
+		return err
+	}
+
+	if cache != nil {
+		if err := cache.Put(m, data); err != nil {
+			cache.warnings = append(cache.warnings, fmt.Sprintf("%s not cached: %s", m.Path, err))
+		}
+	}
+
+	compiled[m.Path] = data
+	return nil
+}
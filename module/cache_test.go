@@ -0,0 +1,242 @@
+package module
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/junbeomlee/jlang"
+)
+
+func TestCompile_Cache(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.j": `import "a"; import "b"; a.x + b.y`,
+		"a.j":    `import "b"; export let x = b.y;`,
+		"b.j":    `export let y = 1;`,
+	})
+
+	compiled := []string{}
+	compile := func(m *Module) ([]byte, error) {
+		compiled = append(compiled, filepath.Base(m.Path))
+		return (&jlang.Bytecode{Source: m.Path}).MarshalBinary()
+	}
+
+	build := func(cache *Cache) map[string][]byte {
+		m, err := NewLoader([]string{}).Load(filepath.Join(dir, "main.j"))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		compiled = []string{}
+		bytecode, err := Compile(m, cache, compile)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		return bytecode
+	}
+
+	tests := []struct {
+		change   func()
+		cache    bool
+		expected []string
+	}{
+		{func() {}, true, []string{"b.j", "a.j", "main.j"}},
+		{func() {}, true, []string{}},
+		{func() {}, false, []string{"b.j", "a.j", "main.j"}},
+		{
+			func() {
+				os.WriteFile(filepath.Join(dir, "main.j"), []byte(`import "a"; import "b"; a.x`), 0644)
+			},
+			true,
+			[]string{"main.j"},
+		},
+		{
+			// transitive import changes invalidate every importing module
+			func() {
+				os.WriteFile(filepath.Join(dir, "b.j"), []byte(`export let y = 2;`), 0644)
+			},
+			true,
+			[]string{"b.j", "a.j", "main.j"},
+		},
+	}
+
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+
+	for i, tt := range tests {
+		tt.change()
+
+		var bytecode map[string][]byte
+		if tt.cache {
+			bytecode = build(NewCache(cacheDir, ""))
+		} else {
+			bytecode = build(nil)
+		}
+
+		if len(bytecode) != 3 {
+			t.Errorf("tests[%d] - bytecode of 3 modules expected. got=%d", i, len(bytecode))
+		}
+
+		if len(compiled) != len(tt.expected) {
+			t.Errorf("tests[%d] - compiled modules wrong. expected=%v, got=%v", i, tt.expected, compiled)
+			continue
+		}
+
+		for j, name := range tt.expected {
+			if compiled[j] != name {
+				t.Errorf("tests[%d] - compiled modules wrong. expected=%v, got=%v", i, tt.expected, compiled)
+				break
+			}
+		}
+	}
+}
+
+func TestCompile_CorruptCache(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.j": `import "a"; import "b"; a.x + b.y`,
+		"a.j":    `export let x = 1;`,
+		"b.j":    `export let y = 2;`,
+	})
+
+	m, err := NewLoader([]string{}).Load(filepath.Join(dir, "main.j"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	compiled := []string{}
+	compile := func(m *Module) ([]byte, error) {
+		compiled = append(compiled, filepath.Base(m.Path))
+		return (&jlang.Bytecode{Source: m.Path}).MarshalBinary()
+	}
+
+	cache := NewCache(t.TempDir(), "")
+	if _, err := Compile(m, cache, compile); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// truncated entry of a and entry of b with a flipped byte
+	data, _ := os.ReadFile(cache.path(m.Imports["a"]))
+	os.WriteFile(cache.path(m.Imports["a"]), data[:len(data)/2], 0644)
+
+	data, _ = os.ReadFile(cache.path(m.Imports["b"]))
+	data[len(data)/2] ^= 0xff
+	os.WriteFile(cache.path(m.Imports["b"]), data, 0644)
+
+	for i, expected := range [][]string{{"a.j", "b.j"}, {}} {
+		compiled = []string{}
+		bytecode, err := Compile(m, cache, compile)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		sort.Strings(compiled)
+		if fmt.Sprint(compiled) != fmt.Sprint(expected) {
+			t.Errorf("run %d - compiled modules wrong. expected=%v, got=%v", i, expected, compiled)
+		}
+
+		for path, data := range bytecode {
+			if _, err := jlang.LoadBytecode(data); err != nil {
+				t.Errorf("run %d - bytecode of %s does not load: %s", i, path, err)
+			}
+		}
+	}
+}
+
+func TestCompile_UnwritableCache(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.j": `import "a"; a.x`,
+		"a.j":    `export let x = 1;`,
+	})
+
+	m, err := NewLoader([]string{}).Load(filepath.Join(dir, "main.j"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	compile := func(m *Module) ([]byte, error) {
+		return (&jlang.Bytecode{Source: m.Path}).MarshalBinary()
+	}
+
+	// cache directory is a file, so no entry can be stored
+	cache := NewCache(filepath.Join(dir, "a.j"), "")
+	bytecode, err := Compile(m, cache, compile)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(bytecode) != 2 {
+		t.Errorf("bytecode of 2 modules expected. got=%d", len(bytecode))
+	}
+
+	if len(cache.Warnings()) != 2 {
+		t.Errorf("2 warnings expected. got=%v", cache.Warnings())
+	}
+}
+
+func TestCache_Prune(t *testing.T) {
+	cache := NewCache(t.TempDir(), "")
+
+	old := &Module{Path: "old.j", Hash: "1", Imports: map[string]*Module{}}
+	used := &Module{Path: "used.j", Hash: "2", Imports: map[string]*Module{}}
+	for _, m := range []*Module{old, used} {
+		data, _ := (&jlang.Bytecode{Source: m.Path}).MarshalBinary()
+		if err := cache.Put(m, data); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		past := time.Now().Add(-MaxCacheAge - time.Hour)
+		os.Chtimes(cache.path(m), past, past)
+	}
+
+	if _, ok := cache.Get(used); !ok {
+		t.Fatalf("entry of used.j not found")
+	}
+
+	m := &Module{Path: "new.j", Hash: "3", Imports: map[string]*Module{}}
+	data, _ := (&jlang.Bytecode{Source: m.Path}).MarshalBinary()
+	if err := cache.Put(m, data); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, tt := range []struct {
+		m      *Module
+		cached bool
+	}{{old, false}, {used, true}, {m, true}} {
+		if _, err := os.Stat(cache.path(tt.m)); (err == nil) != tt.cached {
+			t.Errorf("entry of %s cached=%t expected", tt.m.Path, tt.cached)
+		}
+	}
+}
+
+func TestCache_Key(t *testing.T) {
+	b := &Module{Path: "b.j", Hash: "1", Imports: map[string]*Module{}}
+	a := &Module{Path: "a.j", Hash: "2", Imports: map[string]*Module{"b": b}}
+
+	changed := &Module{Path: "b.j", Hash: "3", Imports: map[string]*Module{}}
+	importsChanged := &Module{Path: "a.j", Hash: "2", Imports: map[string]*Module{"b": changed}}
+
+	cache := NewCache(t.TempDir(), "-O1")
+	if cache.Key(a) == NewCache(t.TempDir(), "-O1").Key(importsChanged) {
+		t.Errorf("key does not change with import")
+	}
+
+	if cache.Key(a) != NewCache(t.TempDir(), "-O1").Key(a) {
+		t.Errorf("key of same module differs")
+	}
+
+	if cache.Key(a) == cache.Key(b) {
+		t.Errorf("keys of different modules are same")
+	}
+
+	moved := &Module{Path: "c.j", Hash: "1", Imports: map[string]*Module{}}
+	if cache.Key(b) == cache.Key(moved) {
+		t.Errorf("keys of same source at different paths are same")
+	}
+
+	if cache.Key(a) == NewCache(t.TempDir(), "-O2").Key(a) {
+		t.Errorf("key does not change with compiler configuration")
+	}
+}
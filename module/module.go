@@ -0,0 +1,184 @@
+package module
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/junbeomlee/jlang"
+	"github.com/junbeomlee/jlang/ast"
+	"github.com/junbeomlee/jlang/parser"
+)
+
+// Extension of source files. It may be omitted in import paths.
+const Extension = ".j"
+
+// Module is a parsed source file with the modules it imports.
+// Each module has its own namespace, names of other modules are reached
+// through the name their module is imported as.
+type Module struct {
+
+	// Absolute path of source file
+	Path string
+
+	// Hex encoded SHA-256 of source
+	Hash string
+
+	Program *ast.Program
+
+	// Imported modules keyed by the name they are bound to
+	Imports map[string]*Module
+
+	// Names declared with export
+	Exports []string
+}
+
+// Exported returns whether module exports given name.
+func (m *Module) Exported(name string) bool {
+	for _, e := range m.Exports {
+		if e == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Loader resolves, parses and caches modules and their imports.
+// Import paths are resolved relative to the importing file first,
+// then relative to each search path in order.
+type Loader struct {
+	searchPaths []string
+
+	// loaded modules keyed by absolute path
+	modules map[string]*Module
+
+	// modules being loaded, importing module first
+	loading []*pending
+}
+
+type pending struct {
+	path string
+	name string
+}
+
+func NewLoader(searchPaths []string) *Loader {
+	return &Loader{
+		searchPaths: searchPaths,
+		modules:     make(map[string]*Module),
+		loading:     []*pending{},
+	}
+}
+
+// Load loads module at given file path with its transitive imports.
+func (l *Loader) Load(path string) (*Module, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return l.load(abs, path)
+}
+
+// Resolve returns absolute path of module imported with importPath
+// from the file at given path.
+func (l *Loader) Resolve(importPath string, from string) (string, error) {
+	name := filepath.FromSlash(importPath)
+	if filepath.Ext(name) == "" {
+		name += Extension
+	}
+
+	candidates := []string{}
+	if filepath.IsAbs(name) {
+		candidates = append(candidates, name)
+	} else {
+		candidates = append(candidates, filepath.Join(filepath.Dir(from), name))
+		for _, dir := range l.searchPaths {
+			candidates = append(candidates, filepath.Join(dir, name))
+		}
+	}
+
+	for _, c := range candidates {
+		if info, err := os.Stat(c); err == nil && !info.IsDir() {
+			return filepath.Abs(c)
+		}
+	}
+
+	return "", fmt.Errorf("cannot find module %q imported from %s, searched %s",
+		importPath, from, strings.Join(candidates, ", "))
+}
+
+func (l *Loader) load(path string, name string) (*Module, error) {
+	if m, ok := l.modules[path]; ok {
+		return m, nil
+	}
+
+	for _, p := range l.loading {
+		if p.path == path {
+			return nil, fmt.Errorf("import cycle: %s", l.chain(name))
+		}
+	}
+
+	l.loading = append(l.loading, &pending{path: path, name: name})
+	defer func() { l.loading = l.loading[:len(l.loading)-1] }()
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser.New(jlang.New(string(src)))
+	program := p.Parse()
+
+	if len(p.Errors()) != 0 {
+		return nil, fmt.Errorf("%s: %s", name, strings.Join(p.Errors(), "\n\t"))
+	}
+
+	m := &Module{
+		Path:    path,
+		Hash:    fmt.Sprintf("%x", sha256.Sum256(src)),
+		Program: program,
+		Imports: make(map[string]*Module),
+		Exports: []string{},
+	}
+
+	for _, stmt := range program.Statements {
+		switch s := stmt.(type) {
+		case *ast.ImportStatement:
+			if _, ok := m.Imports[s.Name()]; ok {
+				return nil, fmt.Errorf("%s: %s is imported twice, line %d",
+					name, s.Name(), s.Token.Line+1)
+			}
+
+			resolved, err := l.Resolve(s.Path.Value, path)
+			if err != nil {
+				return nil, err
+			}
+
+			imported, err := l.load(resolved, s.Path.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			m.Imports[s.Name()] = imported
+		case *ast.ExportStatement:
+			m.Exports = append(m.Exports, s.Names()...)
+		}
+	}
+
+	l.modules[path] = m
+	return m, nil
+}
+
+// chain returns import chain from the first loading module to the module named name,
+// such as "main.j -> lib/a -> lib/b -> lib/a".
+func (l *Loader) chain(name string) string {
+	names := []string{}
+	for _, p := range l.loading {
+		names = append(names, p.name)
+	}
+
+	return strings.Join(append(names, name), " -> ")
+}
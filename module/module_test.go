@@ -0,0 +1,150 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+
+	for name, src := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestLoader_Load(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.j":          `import "lib/strings" as s; import "util"; s.upper(util.name)`,
+		"lib/strings.j":   `import "helper.j"; export fn upper(x) { helper.upper(x) }`,
+		"lib/helper.j":    `export fn upper(x) { x } fn private() { 1 }`,
+		"shared/util.j":   `export let name = "j"; export enum Color { Red, Green }`,
+		"shared/unused.j": `1`,
+	})
+
+	l := NewLoader([]string{filepath.Join(dir, "shared")})
+	m, err := l.Load(filepath.Join(dir, "main.j"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(m.Imports) != 2 {
+		t.Fatalf("main.j does not import 2 modules. got=%d", len(m.Imports))
+	}
+
+	s, ok := m.Imports["s"]
+	if !ok {
+		t.Fatalf("lib/strings is not bound to s. got=%v", m.Imports)
+	}
+
+	if s.Path != filepath.Join(dir, "lib", "strings.j") {
+		t.Errorf("s.Path wrong. got=%q", s.Path)
+	}
+
+	helper, ok := s.Imports["helper"]
+	if !ok {
+		t.Fatalf("lib/helper.j is not bound to helper. got=%v", s.Imports)
+	}
+
+	if !helper.Exported("upper") || helper.Exported("private") {
+		t.Errorf("helper exports wrong. got=%v", helper.Exports)
+	}
+
+	util, ok := m.Imports["util"]
+	if !ok {
+		t.Fatalf("shared/util.j is not bound to util. got=%v", m.Imports)
+	}
+
+	expected := []string{"name", "Color", "Red", "Green"}
+	if strings.Join(util.Exports, ",") != strings.Join(expected, ",") {
+		t.Errorf("util exports wrong. expected=%v, got=%v", expected, util.Exports)
+	}
+
+	again, err := l.Load(filepath.Join(dir, "lib", "strings.j"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if again != s {
+		t.Errorf("lib/strings.j is loaded twice")
+	}
+}
+
+func TestLoader_LoadErrors(t *testing.T) {
+	tests := []struct {
+		files         map[string]string
+		expectedError string
+	}{
+		{
+			map[string]string{
+				"main.j": `import "a"`,
+				"a.j":    `import "b"`,
+				"b.j":    `import "a"`,
+			},
+			"import cycle: main.j -> a -> b -> a",
+		},
+		{
+			map[string]string{
+				"main.j": `import "main"`,
+			},
+			"import cycle: main.j -> main",
+		},
+		{
+			map[string]string{
+				"main.j":  `import "a"; import "lib/a"`,
+				"a.j":     `1`,
+				"lib/a.j": `1`,
+			},
+			"main.j: a is imported twice, line 1",
+		},
+		{
+			map[string]string{
+				"main.j": `import "a"`,
+				"a.j":    `let = 1`,
+			},
+			"a: expected next token to be IDENT, got = instead, line 1, col 5",
+		},
+		{
+			map[string]string{
+				"main.j": `import "missing"`,
+			},
+			`cannot find module "missing" imported from`,
+		},
+	}
+
+	for _, tt := range tests {
+		dir := writeFiles(t, tt.files)
+
+		wd, err := os.Getwd()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Chdir(dir); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = NewLoader([]string{}).Load("main.j")
+		os.Chdir(wd)
+
+		if err == nil {
+			t.Errorf("expected error %q. got none", tt.expectedError)
+			continue
+		}
+
+		if !strings.HasPrefix(err.Error(), tt.expectedError) {
+			t.Errorf("error wrong. expected=%q, got=%q", tt.expectedError, err.Error())
+		}
+	}
+}
@@ -0,0 +1,401 @@
+package optimizer
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/junbeomlee/jlang"
+	"github.com/junbeomlee/jlang/ast"
+)
+
+// Fold simplifies program in place and returns it.
+// Prefix and infix expressions on literals are replaced with their result unless
+// it is only known at runtime, such as on integer overflow or division by zero.
+// Branches of if expressions whose condition is a boolean literal are removed and
+// statements following a return or throw statement are dropped, except function
+// statements which are hoisted.
+func Fold(program *ast.Program) *ast.Program {
+	program.Statements = foldStatements(program.Statements)
+	return program
+}
+
+func foldStatements(stmts []ast.Statement) []ast.Statement {
+	folded := []ast.Statement{}
+
+	for i, stmt := range stmts {
+		folded = append(folded, foldStatement(stmt, i == len(stmts)-1)...)
+
+		if len(folded) == 0 || !exits(folded[len(folded)-1]) {
+			continue
+		}
+
+		for _, unreachable := range stmts[i+1:] {
+			if fs, ok := unreachable.(*ast.FunctionStatement); ok {
+				folded = append(folded, foldStatement(fs, false)...)
+			}
+		}
+		break
+	}
+
+	return folded
+}
+
+// exits returns whether statements following stmt are never run.
+func exits(stmt ast.Statement) bool {
+	switch stmt.(type) {
+	case *ast.ReturnStatement, *ast.ThrowStatement:
+		return true
+	}
+
+	return false
+}
+
+// foldStatement returns statements replacing stmt. Last statement of a block
+// is its value, so it is never removed.
+func foldStatement(stmt ast.Statement, last bool) []ast.Statement {
+	switch s := stmt.(type) {
+	case *ast.LetStatement:
+		s.Value = foldExpression(s.Value)
+	case *ast.ReturnStatement:
+		s.ReturnValue = foldExpression(s.ReturnValue)
+	case *ast.ThrowStatement:
+		s.Value = foldExpression(s.Value)
+	case *ast.ExportStatement:
+		s.Statement = foldStatement(s.Statement, false)[0]
+	case *ast.FunctionStatement:
+		foldExpression(s.Function)
+	case *ast.TryStatement:
+		foldBlock(s.Block)
+		foldBlock(s.Catch)
+		foldBlock(s.Finally)
+	case *ast.ExpressionStatement:
+		if ifExp, ok := s.Expression.(*ast.IFExpression); ok {
+			return foldIfStatement(s, ifExp, last)
+		}
+		s.Expression = foldExpression(s.Expression)
+	}
+
+	return []ast.Statement{stmt}
+}
+
+// foldIfStatement replaces if expression statement with constant condition by statements
+// of the branch taken. Branch declaring names is kept in a block of its own.
+func foldIfStatement(stmt *ast.ExpressionStatement, ifExp *ast.IFExpression, last bool) []ast.Statement {
+	ifExp.Condition = foldExpression(ifExp.Condition)
+
+	condition, ok := ifExp.Condition.(*ast.BooleanLiteral)
+	if !ok {
+		stmt.Expression = foldExpression(ifExp)
+		return []ast.Statement{stmt}
+	}
+
+	branch := ifExp.Alternative
+	if condition.Value {
+		branch = ifExp.Consequence
+	}
+	foldBlock(branch)
+
+	if branch == nil || len(branch.Statements) == 0 {
+		if last {
+			stmt.Expression = null(ifExp.Token)
+			return []ast.Statement{stmt}
+		}
+		return []ast.Statement{}
+	}
+
+	if declares(branch) {
+		stmt.Expression = takenBranch(ifExp, condition, branch)
+		return []ast.Statement{stmt}
+	}
+
+	return branch.Statements
+}
+
+// declares returns whether block declares names in its scope.
+func declares(block *ast.BlockStatement) bool {
+	for _, stmt := range block.Statements {
+		switch stmt.(type) {
+		case *ast.LetStatement, *ast.FunctionStatement, *ast.StructStatement, *ast.EnumStatement:
+			return true
+		}
+	}
+
+	return false
+}
+
+// takenBranch returns if expression running only branch.
+func takenBranch(ifExp *ast.IFExpression, condition *ast.BooleanLiteral, branch *ast.BlockStatement) *ast.IFExpression {
+	ifExp.Condition = boolean(condition.Token, true)
+	ifExp.Consequence = branch
+	ifExp.Alternative = nil
+
+	return ifExp
+}
+
+func foldBlock(block *ast.BlockStatement) {
+	if block != nil {
+		block.Statements = foldStatements(block.Statements)
+	}
+}
+
+func foldExpression(exp ast.Expression) ast.Expression {
+	switch e := exp.(type) {
+	case *ast.PrefixExpression:
+		e.RightExpression = foldExpression(e.RightExpression)
+		if folded := foldPrefix(e); folded != nil {
+			return folded
+		}
+	case *ast.InfixExpression:
+		e.LeftExpression = foldExpression(e.LeftExpression)
+		e.RightExpression = foldExpression(e.RightExpression)
+		if folded := foldInfix(e); folded != nil {
+			return folded
+		}
+	case *ast.IFExpression:
+		return foldIf(e)
+	case *ast.PostfixExpression:
+		e.LeftExpression = foldExpression(e.LeftExpression)
+	case *ast.PipeExpression:
+		e.LeftExpression = foldExpression(e.LeftExpression)
+		e.RightExpression = foldExpression(e.RightExpression)
+	case *ast.InterpolatedString:
+		foldExpressions(e.Parts)
+	case *ast.ArrayLiteral:
+		foldExpressions(e.Elements)
+	case *ast.HashLiteral:
+		for _, pair := range e.Pairs {
+			pair.Key = foldExpression(pair.Key)
+			pair.Value = foldExpression(pair.Value)
+		}
+	case *ast.ListComprehension:
+		e.Element = foldExpression(e.Element)
+		foldClause(e.Clause)
+	case *ast.HashComprehension:
+		e.Key = foldExpression(e.Key)
+		e.Value = foldExpression(e.Value)
+		foldClause(e.Clause)
+	case *ast.MatchExpression:
+		e.Subject = foldExpression(e.Subject)
+		for _, arm := range e.Arms {
+			arm.Guard = foldExpression(arm.Guard)
+			foldBlock(arm.Body)
+		}
+	case *ast.FunctionExpression:
+		for name, value := range e.Defaults {
+			e.Defaults[name] = foldExpression(value)
+		}
+		foldBlock(e.Body)
+	case *ast.SelectorExpression:
+		e.Object = foldExpression(e.Object)
+	case *ast.ChainExpression:
+		e.Chain = foldExpression(e.Chain)
+	case *ast.YieldExpression:
+		e.Value = foldExpression(e.Value)
+	case *ast.RangeExpression:
+		e.Start = foldExpression(e.Start)
+		e.End = foldExpression(e.End)
+	case *ast.IndexExpression:
+		e.Left = foldExpression(e.Left)
+		e.Index = foldExpression(e.Index)
+	case *ast.SliceExpression:
+		e.Left = foldExpression(e.Left)
+		e.Low = foldExpression(e.Low)
+		e.High = foldExpression(e.High)
+	case *ast.CallExpression:
+		e.Function = foldExpression(e.Function)
+		foldExpressions(e.Args)
+	case *ast.SpreadExpression:
+		e.Value = foldExpression(e.Value)
+	case *ast.KeywordArgument:
+		e.Value = foldExpression(e.Value)
+	}
+
+	return exp
+}
+
+func foldExpressions(exps []ast.Expression) {
+	for i, exp := range exps {
+		exps[i] = foldExpression(exp)
+	}
+}
+
+func foldClause(clause *ast.ComprehensionClause) {
+	if clause != nil {
+		clause.Iterable = foldExpression(clause.Iterable)
+		clause.Condition = foldExpression(clause.Condition)
+	}
+}
+
+// foldIf removes branch of if expression with constant condition. The taken branch
+// replaces the if expression when it is a single expression.
+func foldIf(ifExp *ast.IFExpression) ast.Expression {
+	ifExp.Condition = foldExpression(ifExp.Condition)
+	foldBlock(ifExp.Consequence)
+	foldBlock(ifExp.Alternative)
+
+	condition, ok := ifExp.Condition.(*ast.BooleanLiteral)
+	if !ok {
+		return ifExp
+	}
+
+	branch := ifExp.Alternative
+	if condition.Value {
+		branch = ifExp.Consequence
+	}
+
+	if branch == nil || len(branch.Statements) == 0 {
+		return null(ifExp.Token)
+	}
+
+	if len(branch.Statements) == 1 {
+		if stmt, ok := branch.Statements[0].(*ast.ExpressionStatement); ok {
+			return stmt.Expression
+		}
+	}
+
+	return takenBranch(ifExp, condition, branch)
+}
+
+// foldPrefix returns result of prefix expression on literal, nil if it is not known.
+func foldPrefix(e *ast.PrefixExpression) ast.Expression {
+	switch right := e.RightExpression.(type) {
+	case *ast.IntegerLiteral:
+		if e.Operator == "-" && right.Value != math.MinInt64 {
+			return integer(e.Token, -right.Value)
+		}
+	case *ast.BooleanLiteral:
+		if e.Operator == "!" {
+			return boolean(e.Token, !right.Value)
+		}
+	}
+
+	return nil
+}
+
+// foldInfix returns result of infix expression on literals, nil if it is not known.
+func foldInfix(e *ast.InfixExpression) ast.Expression {
+	if e.Operator == "??" {
+		switch e.LeftExpression.(type) {
+		case *ast.NullLiteral:
+			return e.RightExpression
+		case *ast.IntegerLiteral, *ast.BooleanLiteral, *ast.StringLiteral:
+			return e.LeftExpression
+		}
+		return nil
+	}
+
+	switch left := e.LeftExpression.(type) {
+	case *ast.IntegerLiteral:
+		if right, ok := e.RightExpression.(*ast.IntegerLiteral); ok {
+			return foldIntegers(e.Token, e.Operator, left.Value, right.Value)
+		}
+	case *ast.BooleanLiteral:
+		if right, ok := e.RightExpression.(*ast.BooleanLiteral); ok {
+			switch e.Operator {
+			case "==":
+				return boolean(e.Token, left.Value == right.Value)
+			case "!=":
+				return boolean(e.Token, left.Value != right.Value)
+			}
+		}
+	case *ast.StringLiteral:
+		if right, ok := e.RightExpression.(*ast.StringLiteral); ok {
+			switch e.Operator {
+			case "+":
+				return str(left.Token, left.Value+right.Value)
+			case "==":
+				return boolean(e.Token, left.Value == right.Value)
+			case "!=":
+				return boolean(e.Token, left.Value != right.Value)
+			}
+		}
+	}
+
+	return nil
+}
+
+// arithmetic are opcodes of arithmetic operators, folded the same way as in bytecode.
+var arithmetic = map[string]jlang.Opcode{
+	"+": jlang.OpAdd,
+	"-": jlang.OpSub,
+	"*": jlang.OpMul,
+	"/": jlang.OpDiv,
+}
+
+func foldIntegers(token jlang.Token, operator string, l, r int64) ast.Expression {
+	if op, ok := arithmetic[operator]; ok {
+		if result, ok := jlang.FoldIntegers(op, l, r); ok {
+			return integer(token, result)
+		}
+		return nil
+	}
+
+	switch operator {
+	case "<":
+		return boolean(token, l < r)
+	case ">":
+		return boolean(token, l > r)
+	case "==":
+		return boolean(token, l == r)
+	case "!=":
+		return boolean(token, l != r)
+	}
+
+	return nil
+}
+
+// integer returns integer literal of value at position of token.
+func integer(token jlang.Token, value int64) *ast.IntegerLiteral {
+	token.Type = jlang.INT
+	token.Val = strconv.FormatInt(value, 10)
+
+	return &ast.IntegerLiteral{Token: token, Value: value}
+}
+
+// boolean returns boolean literal of value at position of token.
+func boolean(token jlang.Token, value bool) *ast.BooleanLiteral {
+	token.Type = jlang.FALSE
+	if value {
+		token.Type = jlang.TRUE
+	}
+	token.Val = strconv.FormatBool(value)
+
+	return &ast.BooleanLiteral{Token: token, Value: value}
+}
+
+// str returns string literal of value at position of token. Its source form is
+// escaped, so joined "$" and "{" do not start an interpolation when it is printed.
+func str(token jlang.Token, value string) *ast.StringLiteral {
+	var val strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case c == '\\' || c == '"':
+			val.WriteByte('\\')
+			val.WriteByte(c)
+		case c == '\n':
+			val.WriteString(`\n`)
+		case c == '\t':
+			val.WriteString(`\t`)
+		case c == '\r':
+			val.WriteString(`\r`)
+		case c == '$' && i+1 < len(value) && value[i+1] == '{':
+			val.WriteString(`\$`)
+		default:
+			val.WriteByte(c)
+		}
+	}
+
+	token.Type = jlang.STRING
+	token.Val = val.String()
+
+	return &ast.StringLiteral{Token: token, Value: value}
+}
+
+// null returns null literal at position of token.
+func null(token jlang.Token) *ast.NullLiteral {
+	token.Type = jlang.NULL
+	token.Val = "null"
+
+	return &ast.NullLiteral{Token: token}
+}
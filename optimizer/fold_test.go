@@ -0,0 +1,131 @@
+package optimizer
+
+import (
+	"testing"
+
+	"github.com/junbeomlee/jlang"
+	"github.com/junbeomlee/jlang/ast"
+	"github.com/junbeomlee/jlang/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(jlang.New(input))
+	program := p.Parse()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser has %d errors for %q: %v", len(p.Errors()), input, p.Errors())
+	}
+
+	return program
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + 2 * 3", "7"},
+		{"-5 - 10", "-15"},
+		{"x + 2 * 3", "(x + 6)"},
+		{"(1 + x) + 2", "((1 + x) + 2)"},
+		{"10 / 3", "3"},
+		{"10 / 0", "(10 / 0)"},
+		{"9223372036854775807 + 1", "(9223372036854775807 + 1)"},
+		{"4611686018427387904 * 2", "(4611686018427387904 * 2)"},
+		{"-9223372036854775807 - 1", "-9223372036854775808"},
+		{"(-9223372036854775807 - 1) / -1", "(-9223372036854775808 / -1)"},
+		{"1 < 2", "true"},
+		{"1 == 2 == false", "true"},
+		{"!true != false", "false"},
+		{"!5", "(!5)"},
+		{`"a" + "b\n"`, `"ab\n"`},
+		{`"$" + "{x}"`, `"\${x}"`},
+		{`"\$" + "{x}" + "$y"`, `"\${x}$y"`},
+		{`"\"" + "\\"`, `"\"\\"`},
+		{`"a" == "a"`, "true"},
+		{`"a" - "b"`, `("a" - "b")`},
+		{"null ?? 1 + 1", "2"},
+		{"3 ?? x", "3"},
+		{"x ?? 3", "(x ?? 3)"},
+		{"let x = [1 + 1, {2 * 2: f(3 - 3)}];", "let x = [2, {4: f(0)}];"},
+		{"fn(a = 1 + 1) { a * (2 + 2) }", "fn(a = 2){(a * 4)}"},
+	}
+
+	for _, tt := range tests {
+		program := Fold(parse(t, tt.input))
+
+		if program.String() != tt.expected {
+			t.Errorf("folded program wrong for %q. expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+// Folded strings must read back as the same string rather than an interpolation.
+func TestFold_StringSource(t *testing.T) {
+	for _, input := range []string{`"$" + "{x}"`, `"a\t" + "\"\${" + "\\"`} {
+		folded := Fold(parse(t, input)).String()
+
+		stmt, ok := parse(t, folded).Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("folded %q is not an expression statement. got=%q", input, folded)
+		}
+
+		literal, ok := stmt.Expression.(*ast.StringLiteral)
+		if !ok {
+			t.Errorf("folded %q does not read back as string literal. got=%q", input, folded)
+			continue
+		}
+
+		expected := Fold(parse(t, input)).Statements[0].(*ast.ExpressionStatement).Expression.(*ast.StringLiteral).Value
+		if literal.Value != expected {
+			t.Errorf("folded %q reads back wrong. expected=%q, got=%q", input, expected, literal.Value)
+		}
+	}
+}
+
+func TestFold_Branches(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = if (true) { 1 } else { 2 };", "let x = 1;"},
+		{"let x = if (1 > 2) { 1 } else { 2 + 3 };", "let x = 5;"},
+		{"let x = if (false) { 1 };", "let x = null;"},
+		{"let x = if (y) { 1 + 1 } else { 2 };", "let x = if(y){2\n}else{2\n};"},
+		{"if (true) { f(); g() } else { h() }; k()", "f()g()k()"},
+		{"if (false) { f() }; k()", "k()"},
+		{"k(); if (false) { f() }", "k()null"},
+		{"if (!false) { let y = 1; y }", "if(true){let y = 1;\ny\n}else"},
+		{"if (false) { f() } else { let y = 1; y }", "if(true){let y = 1;\ny\n}else"},
+	}
+
+	for _, tt := range tests {
+		program := Fold(parse(t, tt.input))
+
+		if program.String() != tt.expected {
+			t.Errorf("folded program wrong for %q. expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestFold_Unreachable(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn() { return 1; f(); g() }", "fn(){return 1;}"},
+		{"fn() { throw e; f() }", "fn(){throw e;}"},
+		{"fn() { return f(); fn g() { 1 + 1 } let x = 1; }", "fn(){return f();fn g(){2}}"},
+		{"fn() { if (true) { return 1 }; f() }", "fn(){return 1;}"},
+		{"fn() { if (x) { return 1 }; f() }", "fn(){if(x){return 1;\n}elsef()}"},
+		{"fn() { try { return 1; f() } catch (e) { g() } }", "fn(){try{return 1;}catch(e){g()}}"},
+	}
+
+	for _, tt := range tests {
+		program := Fold(parse(t, tt.input))
+
+		if program.String() != tt.expected {
+			t.Errorf("folded program wrong for %q. expected=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+}
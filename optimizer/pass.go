@@ -0,0 +1,225 @@
+package optimizer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/junbeomlee/jlang"
+	"github.com/junbeomlee/jlang/ast"
+)
+
+// Names of the points of the pipeline which are not passes, IR can be dumped after them too.
+const (
+	Parse   = "parse"
+	Compile = "compile"
+)
+
+// MaxLevel is the highest optimization level.
+const MaxLevel = 2
+
+// Pass is a named optimization over AST or bytecode. Exactly one of AST and Bytecode is set.
+type Pass struct {
+	Name string
+
+	// Lowest optimization level which runs the pass
+	Level int
+
+	AST      func(*ast.Program) *ast.Program
+	Bytecode func(*jlang.Bytecode) error
+}
+
+// Passes in the order they run. AST passes run before compilation and
+// bytecode passes after it.
+var Passes = []*Pass{
+	{Name: "fold", Level: 1, AST: Fold},
+	{Name: "peephole", Level: 2, Bytecode: jlang.Peephole},
+}
+
+func lookupPass(name string) *Pass {
+	for _, pass := range Passes {
+		if pass.Name == name {
+			return pass
+		}
+	}
+
+	return nil
+}
+
+func unknownPass(name string) error {
+	names := []string{Parse}
+	for _, pass := range Passes {
+		if pass.AST != nil {
+			names = append(names, pass.Name)
+		}
+	}
+
+	names = append(names, Compile)
+	for _, pass := range Passes {
+		if pass.Bytecode != nil {
+			names = append(names, pass.Name)
+		}
+	}
+
+	return fmt.Errorf("unknown pass %q, passes are %s", name, strings.Join(names, ", "))
+}
+
+// Compiler wraps jlang.Compiler running passes of its optimization level before
+// and after compilation.
+type Compiler struct {
+	level int
+
+	// compiles program after AST passes, replaced in tests
+	compile func(*ast.Program) *jlang.Bytecode
+
+	// passes enabled or disabled regardless of level
+	overrides map[string]bool
+
+	dumpAfter string
+	dump      io.Writer
+}
+
+func NewCompiler(level int) *Compiler {
+	if level < 0 {
+		level = 0
+	}
+
+	if level > MaxLevel {
+		level = MaxLevel
+	}
+
+	return &Compiler{
+		level:     level,
+		compile:   compileProgram,
+		overrides: make(map[string]bool),
+	}
+}
+
+func compileProgram(program *ast.Program) *jlang.Bytecode {
+	return jlang.NewCompiler().Bytecode()
+}
+
+// Enable runs pass regardless of optimization level.
+func (c *Compiler) Enable(name string) error {
+	if lookupPass(name) == nil {
+		return unknownPass(name)
+	}
+
+	c.overrides[name] = true
+	return nil
+}
+
+// Disable skips pass regardless of optimization level.
+func (c *Compiler) Disable(name string) error {
+	if lookupPass(name) == nil {
+		return unknownPass(name)
+	}
+
+	c.overrides[name] = false
+	return nil
+}
+
+// Enabled returns whether pass runs.
+func (c *Compiler) Enabled(name string) bool {
+	if enabled, ok := c.overrides[name]; ok {
+		return enabled
+	}
+
+	pass := lookupPass(name)
+	return pass != nil && pass.Level <= c.level
+}
+
+// DumpAfter writes IR to w after pass or after Parse or Compile, whether the pass
+// runs or not. AST is written as source and bytecode as disassembly.
+func (c *Compiler) DumpAfter(name string, w io.Writer) error {
+	if name != Parse && name != Compile && lookupPass(name) == nil {
+		return unknownPass(name)
+	}
+
+	c.dumpAfter = name
+	c.dump = w
+	return nil
+}
+
+// String returns optimization level and passes which run, such as "-O2 fold,peephole".
+// Output of compilers with the same string is the same.
+func (c *Compiler) String() string {
+	names := []string{}
+	for _, pass := range Passes {
+		if c.Enabled(pass.Name) {
+			names = append(names, pass.Name)
+		}
+	}
+
+	return strings.TrimSpace(fmt.Sprintf("-O%d %s", c.level, strings.Join(names, ",")))
+}
+
+// Compile runs AST passes over program, compiles it and runs bytecode passes
+// over the result, which is verified first. Program is changed in place by AST passes.
+func (c *Compiler) Compile(program *ast.Program) (*jlang.Bytecode, error) {
+	if err := c.dumpProgram(Parse, program); err != nil {
+		return nil, err
+	}
+
+	for _, pass := range Passes {
+		if pass.AST == nil {
+			continue
+		}
+
+		if c.Enabled(pass.Name) {
+			program = pass.AST(program)
+		}
+
+		if err := c.dumpProgram(pass.Name, program); err != nil {
+			return nil, err
+		}
+	}
+
+	bytecode := c.compile(program)
+	if err := c.dumpBytecode(Compile, bytecode); err != nil {
+		return nil, err
+	}
+
+	if err := jlang.Verify(bytecode); err != nil {
+		return nil, err
+	}
+
+	for _, pass := range Passes {
+		if pass.Bytecode == nil {
+			continue
+		}
+
+		if c.Enabled(pass.Name) {
+			if err := pass.Bytecode(bytecode); err != nil {
+				return nil, fmt.Errorf("%s pass: %s", pass.Name, err)
+			}
+		}
+
+		if err := c.dumpBytecode(pass.Name, bytecode); err != nil {
+			return nil, err
+		}
+	}
+
+	return bytecode, nil
+}
+
+func (c *Compiler) dumpProgram(name string, program *ast.Program) error {
+	if c.dumpAfter != name {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(c.dump, "after %s:\n%s\n", name, program)
+	return err
+}
+
+func (c *Compiler) dumpBytecode(name string, bytecode *jlang.Bytecode) error {
+	if c.dumpAfter != name {
+		return nil
+	}
+
+	if _, err := fmt.Fprintf(c.dump, "after %s:\n", name); err != nil {
+		return err
+	}
+
+	return jlang.Disassemble(c.dump, bytecode, "")
+}
@@ -0,0 +1,98 @@
+package optimizer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/junbeomlee/jlang"
+	"github.com/junbeomlee/jlang/ast"
+)
+
+func TestCompiler_Enabled(t *testing.T) {
+	tests := []struct {
+		level    int
+		enable   []string
+		disable  []string
+		expected string
+	}{
+		{0, nil, nil, "-O0"},
+		{1, nil, nil, "-O1 fold"},
+		{2, nil, nil, "-O2 fold,peephole"},
+		{5, nil, nil, "-O2 fold,peephole"},
+		{0, []string{"peephole"}, nil, "-O0 peephole"},
+		{2, nil, []string{"fold"}, "-O2 peephole"},
+		{1, []string{"fold"}, []string{"fold"}, "-O1"},
+	}
+
+	for i, tt := range tests {
+		c := NewCompiler(tt.level)
+		for _, name := range tt.enable {
+			if err := c.Enable(name); err != nil {
+				t.Fatalf("tests[%d] - %s", i, err)
+			}
+		}
+		for _, name := range tt.disable {
+			if err := c.Disable(name); err != nil {
+				t.Fatalf("tests[%d] - %s", i, err)
+			}
+		}
+
+		if c.String() != tt.expected {
+			t.Errorf("tests[%d] - passes wrong. expected=%q, got=%q", i, tt.expected, c.String())
+		}
+	}
+}
+
+func TestCompiler_UnknownPass(t *testing.T) {
+	expected := `unknown pass "inline", passes are parse, fold, compile, peephole`
+
+	c := NewCompiler(1)
+	for i, err := range []error{c.Enable("inline"), c.Disable("inline"), c.DumpAfter("inline", &bytes.Buffer{})} {
+		if err == nil || err.Error() != expected {
+			t.Errorf("tests[%d] - error wrong. expected=%q, got=%v", i, expected, err)
+		}
+	}
+}
+
+func TestCompiler_DumpAfter(t *testing.T) {
+	tests := []struct {
+		level    int
+		pass     string
+		expected string
+	}{
+		{1, Parse, "after parse:\nlet x = (1 + 2);\n"},
+		{1, "fold", "after fold:\nlet x = 3;\n"},
+		{0, "fold", "after fold:\nlet x = (1 + 2);\n"},
+		{1, Compile, "after compile:\ntop level code:\n"},
+		{2, "peephole", "after peephole:\ntop level code:\n"},
+	}
+
+	for i, tt := range tests {
+		out := &bytes.Buffer{}
+
+		c := NewCompiler(tt.level)
+		if err := c.DumpAfter(tt.pass, out); err != nil {
+			t.Fatalf("tests[%d] - %s", i, err)
+		}
+
+		if _, err := c.Compile(parse(t, "let x = 1 + 2;")); err != nil {
+			t.Fatalf("tests[%d] - %s", i, err)
+		}
+
+		if out.String() != tt.expected {
+			t.Errorf("tests[%d] - dump wrong. expected=%q, got=%q", i, tt.expected, out.String())
+		}
+	}
+}
+
+func TestCompiler_Verify(t *testing.T) {
+	c := NewCompiler(2)
+	c.compile = func(*ast.Program) *jlang.Bytecode {
+		return &jlang.Bytecode{Instructions: jlang.Instructions{byte(jlang.OpPop)}}
+	}
+
+	expected := "invalid bytecode in top level code at offset 0: stack underflow, OpPop needs 1 values but stack has 0"
+	if _, err := c.Compile(parse(t, "1")); err == nil || err.Error() != expected {
+		t.Errorf("error wrong. expected=%q, got=%v", expected, err)
+	}
+}
@@ -1,9 +1,11 @@
 package parser
 
 import (
+	"bytes"
 	"fmt"
 
 	"strconv"
+	"strings"
 
 	"github.com/junbeomlee/jlang"
 	"github.com/junbeomlee/jlang/ast"
@@ -12,24 +14,38 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	PIPE
+	NULLISH
 	EQUALS
 	LESSGREATER
+	RANGE
 	SUM
 	PRODUCT
 	PREFIX
 	CALL
+	MEMBER  = CALL
+	POSTFIX = CALL
+	INDEX   = CALL
 )
 
 var precedences = map[jlang.TokenType]int{
-	jlang.EQ:       EQUALS,
-	jlang.NOT_EQ:   EQUALS,
-	jlang.LT:       LESSGREATER,
-	jlang.GT:       LESSGREATER,
-	jlang.PLUS:     SUM,
-	jlang.MINUS:    SUM,
-	jlang.SLASH:    PRODUCT,
-	jlang.ASTERISK: PRODUCT,
-	jlang.LPAREN:   CALL,
+	jlang.PIPE:            PIPE,
+	jlang.NULLISH:         NULLISH,
+	jlang.EQ:              EQUALS,
+	jlang.NOT_EQ:          EQUALS,
+	jlang.LT:              LESSGREATER,
+	jlang.GT:              LESSGREATER,
+	jlang.RANGE:           RANGE,
+	jlang.RANGE_INCLUSIVE: RANGE,
+	jlang.PLUS:            SUM,
+	jlang.MINUS:           SUM,
+	jlang.SLASH:           PRODUCT,
+	jlang.ASTERISK:        PRODUCT,
+	jlang.LPAREN:          CALL,
+	jlang.DOT:             MEMBER,
+	jlang.QUESTION_DOT:    MEMBER,
+	jlang.QUESTION:        POSTFIX,
+	jlang.LBRACKET:        INDEX,
 }
 
 // Expression
@@ -37,41 +53,65 @@ var precedences = map[jlang.TokenType]int{
 //  2. IntegerLiteralExpression:  <Int>
 // 	3. PrefixExpression: 		  <prefix operator><expression>
 //  4. InfixExpression: 		  <expression><infix operator><expression>
+//  5. PostfixExpression: 		  <expression><postfix operator>
 
 type (
-	prefixParsefn func() ast.Expression
-	infixParsefn  func(expression ast.Expression) ast.Expression
+	prefixParsefn  func() ast.Expression
+	infixParsefn   func(expression ast.Expression) ast.Expression
+	postfixParsefn func(expression ast.Expression) ast.Expression
 )
 
 type Parser struct {
-	l      *jlang.Lexer
-	errors []string
+	l        *jlang.Lexer
+	errors   []string
+	warnings []string
 
 	curToken  jlang.Token
 	nextToken jlang.Token
 
-	prefixParsefns map[jlang.TokenType]prefixParsefn
-	infixParsefns  map[jlang.TokenType]infixParsefn
+	prefixParsefns  map[jlang.TokenType]prefixParsefn
+	infixParsefns   map[jlang.TokenType]infixParsefn
+	postfixParsefns map[jlang.TokenType]postfixParsefn
+
+	// function literals enclosing current token, innermost last
+	functions []*ast.FunctionExpression
+
+	// declared names of enclosing blocks, innermost last
+	scopes []scope
+
+	// field accesses of expressions which may be elements of comprehensions, innermost last
+	deferred []*deferredChecks
 }
 
 func New(l *jlang.Lexer) *Parser {
 	p := &Parser{
-		l:      l,
-		errors: []string{},
+		l:        l,
+		errors:   []string{},
+		warnings: []string{},
 	}
+	p.openScope()
+	p.declareBuiltins()
 	p.next()
 	p.next()
 
 	p.prefixParsefns = make(map[jlang.TokenType]prefixParsefn)
 	p.registerPrefix(jlang.IDENT, p.parseIdentifier)
 	p.registerPrefix(jlang.INT, p.parseIntegerLiteral)
+	p.registerPrefix(jlang.STRING, p.parseStringLiteral)
+	p.registerPrefix(jlang.STRING_PART, p.parseInterpolatedString)
+	p.registerPrefix(jlang.ILLEGAL, p.parseIllegal)
 	p.registerPrefix(jlang.BANG, p.parsePrefixExpression)
 	p.registerPrefix(jlang.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(jlang.TRUE, p.parseBooleanLiteral)
 	p.registerPrefix(jlang.FALSE, p.parseBooleanLiteral)
+	p.registerPrefix(jlang.NULL, p.parseNullLiteral)
 	p.registerPrefix(jlang.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(jlang.IF, p.parseIfExpression)
 	p.registerPrefix(jlang.FUNCTION, p.parseFunctionExpression)
+	p.registerPrefix(jlang.MATCH, p.parseMatchExpression)
+	p.registerPrefix(jlang.YIELD, p.parseYieldExpression)
+	p.registerPrefix(jlang.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(jlang.LBRACE, p.parseHashLiteral)
 
 	p.infixParsefns = make(map[jlang.TokenType]infixParsefn)
 	p.registerInfix(jlang.EQ, p.parseInfixExpression)
@@ -83,6 +123,16 @@ func New(l *jlang.Lexer) *Parser {
 	p.registerInfix(jlang.LT, p.parseInfixExpression)
 	p.registerInfix(jlang.GT, p.parseInfixExpression)
 	p.registerInfix(jlang.LPAREN, p.parseCallExpression)
+	p.registerInfix(jlang.PIPE, p.parsePipeExpression)
+	p.registerInfix(jlang.DOT, p.parseSelectorExpression)
+	p.registerInfix(jlang.QUESTION_DOT, p.parseOptionalChain)
+	p.registerInfix(jlang.NULLISH, p.parseInfixExpression)
+	p.registerInfix(jlang.RANGE, p.parseRangeExpression)
+	p.registerInfix(jlang.RANGE_INCLUSIVE, p.parseRangeExpression)
+	p.registerInfix(jlang.LBRACKET, p.parseIndexExpression)
+
+	p.postfixParsefns = make(map[jlang.TokenType]postfixParsefn)
+	p.registerPostfix(jlang.QUESTION, p.parsePropagateExpression)
 
 	return p
 }
@@ -111,6 +161,10 @@ func (p *Parser) registerInfix(tokenType jlang.TokenType, fn infixParsefn) {
 	p.infixParsefns[tokenType] = fn
 }
 
+func (p *Parser) registerPostfix(tokenType jlang.TokenType, fn postfixParsefn) {
+	p.postfixParsefns[tokenType] = fn
+}
+
 func (p *Parser) parseBooleanLiteral() ast.Expression {
 	booleanLiteral := &ast.BooleanLiteral{Token: p.curToken}
 	b, err := strconv.ParseBool(p.curToken.Val)
@@ -123,6 +177,10 @@ func (p *Parser) parseBooleanLiteral() ast.Expression {
 	return booleanLiteral
 }
 
+func (p *Parser) parseNullLiteral() ast.Expression {
+	return &ast.NullLiteral{Token: p.curToken}
+}
+
 func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Val}
 }
@@ -140,6 +198,98 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	return integerLiteral
 }
 
+func (p *Parser) parseStringLiteral() ast.Expression {
+	stringLiteral := &ast.StringLiteral{Token: p.curToken}
+
+	v, err := unescape(p.curToken.Val)
+	if err != nil {
+		p.Error(fmt.Sprintf("could not parse %q as string: %s, line %d, col %d",
+			p.curToken.Val, err, p.curToken.Line+1, p.curToken.Column+1))
+		return nil
+	}
+
+	stringLiteral.Value = v
+	return stringLiteral
+}
+
+// parseInterpolatedString parses <string_part> <expression> ... <string>
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	exp := &ast.InterpolatedString{Token: p.curToken, Parts: []ast.Expression{}}
+
+	for {
+		part := p.parseStringLiteral()
+		if part == nil {
+			return nil
+		}
+		exp.Parts = append(exp.Parts, part)
+
+		if p.curTokenIs(jlang.STRING) {
+			return exp
+		}
+
+		p.next()
+		part = p.parseExpression(LOWEST)
+		if part == nil {
+			return nil
+		}
+		exp.Parts = append(exp.Parts, part)
+
+		if p.peekTokenIs(jlang.ILLEGAL) {
+			p.next()
+			return p.parseIllegal()
+		}
+
+		if !p.peekTokenIs(jlang.STRING) && !p.peekTokenIs(jlang.STRING_PART) {
+			p.peekError(jlang.STRING)
+			return nil
+		}
+		p.next()
+	}
+}
+
+// parseIllegal reports token the lexer could not scan, such as unterminated string.
+func (p *Parser) parseIllegal() ast.Expression {
+	if strings.HasPrefix(p.curToken.Val, `"`) {
+		p.Error(fmt.Sprintf("unterminated string, line %d, col %d", p.curToken.Line+1, p.curToken.Column+1))
+		return nil
+	}
+
+	p.Error(fmt.Sprintf("no prefix parse function for %s found", p.curToken.Type))
+	return nil
+}
+
+// unescape resolves escape sequences \n, \t, \r, \\, \" and \$ of string.
+func unescape(s string) (string, error) {
+	var out bytes.Buffer
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			out.WriteByte(s[i])
+			continue
+		}
+
+		i++
+		if i == len(s) {
+			return "", fmt.Errorf("incomplete escape sequence")
+		}
+
+		switch s[i] {
+		case 'n':
+			out.WriteByte('\n')
+		case 't':
+			out.WriteByte('\t')
+		case 'r':
+			out.WriteByte('\r')
+		case '\\', '"', '$':
+			out.WriteByte(s[i])
+		default:
+			return "", fmt.Errorf("unknown escape sequence \\%c", s[i])
+		}
+	}
+
+	return out.String(), nil
+}
+
 func (p *Parser) Error(msg string) {
 	p.errors = append(p.errors, msg)
 }
@@ -148,6 +298,16 @@ func (p *Parser) Errors() []string {
 	return p.errors
 }
 
+// Warning records a problem which does not stop the program from running,
+// such as unreachable code.
+func (p *Parser) Warning(msg string) {
+	p.warnings = append(p.warnings, msg)
+}
+
+func (p *Parser) Warnings() []string {
+	return p.warnings
+}
+
 func (p *Parser) next() {
 	p.curToken = p.nextToken
 	p.nextToken = p.l.NextToken()
@@ -197,6 +357,23 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseLetStatement()
 	case jlang.RETURN:
 		return p.parseReturnStatement()
+	case jlang.FUNCTION:
+		if p.peekTokenIs(jlang.IDENT) || p.peekTokenIs(jlang.ASTERISK) {
+			return p.parseFunctionStatement()
+		}
+		return p.parseExpressionStatement()
+	case jlang.STRUCT:
+		return p.parseStructStatement()
+	case jlang.ENUM:
+		return p.parseEnumStatement()
+	case jlang.THROW:
+		return p.parseThrowStatement()
+	case jlang.TRY:
+		return p.parseTryStatement()
+	case jlang.IMPORT:
+		return p.parseImportStatement()
+	case jlang.EXPORT:
+		return p.parseExportStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -205,12 +382,24 @@ func (p *Parser) parseStatement() ast.Statement {
 func (p *Parser) parseLetStatement() ast.Statement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 
-	if !p.expectPeek(jlang.IDENT) {
-		// errors
-		return nil
-	}
+	if p.peekTokenIs(jlang.LBRACKET) || p.peekTokenIs(jlang.LBRACE) {
+		p.next()
+
+		stmt.Pattern = p.parsePattern()
+		if stmt.Pattern == nil {
+			return nil
+		}
+
+		p.checkDuplicateBindings(stmt.Pattern)
+		p.checkIrrefutable(stmt.Pattern, "let statement")
+	} else {
+		if !p.expectPeek(jlang.IDENT) {
+			// errors
+			return nil
+		}
 
-	stmt.Ident = &ast.Identifier{Token: p.curToken, Value: p.curToken.Val}
+		stmt.Ident = &ast.Identifier{Token: p.curToken, Value: p.curToken.Val}
+	}
 
 	if !p.expectPeek(jlang.ASSIGN) {
 		return nil
@@ -220,6 +409,17 @@ func (p *Parser) parseLetStatement() ast.Statement {
 
 	stmt.Value = p.parseExpression(LOWEST)
 
+	// name anonymous function after the variable it is bound to
+	if fn, ok := stmt.Value.(*ast.FunctionExpression); ok && fn.Name == "" && stmt.Ident != nil {
+		fn.Name = stmt.Ident.Value
+	}
+
+	if stmt.Pattern != nil {
+		p.declarePattern(stmt.Pattern)
+	} else {
+		p.declare(stmt.Ident.Value, &binding{structType: p.structTypeOf(stmt.Value)})
+	}
+
 	if p.peekTokenIs(jlang.SEMICOLON) {
 		p.next()
 	}
@@ -227,214 +427,1413 @@ func (p *Parser) parseLetStatement() ast.Statement {
 	return stmt
 }
 
-func (p *Parser) parseReturnStatement() ast.Statement {
-
-	// Define statement
-	stmt := &ast.ReturnStatement{Token: p.curToken}
+// parsePattern parses a binding pattern starting at current token.
+//  1. Identifier:    <ident>
+//  2. ArrayPattern:  [<pattern>, ..., ...<ident>]
+//  3. HashPattern:   {<ident>, <ident>: <pattern>, ...}
+//  4. WildcardPattern: <_>
+//  5. LiteralPattern:  <int>, <-int>, <string>, <true>, <false>, <null>
+//  6. VariantPattern:  <variant>(<pattern>, ...), <variant>
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.curToken.Type {
+	case jlang.IDENT:
+		if p.curToken.Val == "_" {
+			return &ast.WildcardPattern{Token: p.curToken}
+		}
+		if variant, _ := p.lookupVariant(p.curToken.Val); variant != nil || p.peekTokenIs(jlang.LPAREN) {
+			return p.parseVariantPattern()
+		}
+		return &ast.Identifier{Token: p.curToken, Value: p.curToken.Val}
+	case jlang.INT, jlang.STRING, jlang.TRUE, jlang.FALSE, jlang.NULL:
+		value := p.prefixParsefns[p.curToken.Type]()
+		if value == nil {
+			return nil
+		}
+		return &ast.LiteralPattern{Token: p.curToken, Value: value}
+	case jlang.MINUS:
+		if !p.peekTokenIs(jlang.INT) {
+			p.peekError(jlang.INT)
+			return nil
+		}
+		return &ast.LiteralPattern{Token: p.curToken, Value: p.parsePrefixExpression()}
+	case jlang.LBRACKET:
+		return p.parseArrayPattern()
+	case jlang.LBRACE:
+		return p.parseHashPattern()
+	default:
+		p.Error(fmt.Sprintf("expected pattern, got %s instead, line %d, col %d",
+			p.curToken.Type, p.curToken.Line+1, p.curToken.Column+1))
+		return nil
+	}
+}
 
-	// Check next
-	p.next()
-	stmt.ReturnValue = p.parseExpression(LOWEST)
+func (p *Parser) parseVariantPattern() ast.Pattern {
+	pattern := &ast.VariantPattern{
+		Token: p.curToken,
+		Name:  &ast.Identifier{Token: p.curToken, Value: p.curToken.Val},
+		Args:  []ast.Pattern{},
+	}
 
-	if p.peekTokenIs(jlang.SEMICOLON) {
+	if p.peekTokenIs(jlang.LPAREN) {
 		p.next()
-	}
 
-	return stmt
-}
+		for !p.peekTokenIs(jlang.RPAREN) {
+			p.next()
 
-func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
-	stmt := &ast.ExpressionStatement{Token: p.curToken}
+			arg := p.parsePattern()
+			if arg == nil {
+				return nil
+			}
+			pattern.Args = append(pattern.Args, arg)
 
-	stmt.Expression = p.parseExpression(LOWEST)
+			if !p.peekTokenIs(jlang.RPAREN) && !p.expectPeek(jlang.COMMA) {
+				return nil
+			}
+		}
 
-	if p.peekTokenIs(jlang.SEMICOLON) {
 		p.next()
 	}
 
-	return stmt
-}
-
-func (p *Parser) parseExpression(precedence int) ast.Expression {
+	variant, _ := p.lookupVariant(pattern.Name.Value)
+	if variant == nil {
+		p.Error(fmt.Sprintf("unknown variant %s, line %d, col %d",
+			pattern.Name.Value, pattern.Token.Line+1, pattern.Token.Column+1))
+		return nil
+	}
 
-	prefix := p.prefixParsefns[p.curToken.Type]
-	if prefix == nil {
-		p.Error(fmt.Sprintf("no prefix parse function for %s found", p.curToken.Type))
+	if len(pattern.Args) != len(variant.Fields) {
+		p.Error(fmt.Sprintf("variant pattern %s has %d fields, want %d, line %d, col %d",
+			pattern.String(), len(pattern.Args), len(variant.Fields), pattern.Token.Line+1, pattern.Token.Column+1))
 		return nil
 	}
 
-	leftExp := prefix()
+	return pattern
+}
 
-	for !p.peekTokenIs(jlang.SEMICOLON) && precedence < p.peekPrecedence() {
-		infix := p.infixParsefns[p.nextToken.Type]
-		if infix == nil {
-			return leftExp
-		}
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.curToken, Elements: []ast.Pattern{}}
 
+	for !p.peekTokenIs(jlang.RBRACKET) {
 		p.next()
-		leftExp = infix(leftExp)
-	}
 
-	return leftExp
-}
+		if p.curTokenIs(jlang.ELLIPSIS) {
+			if !p.expectPeek(jlang.IDENT) {
+				return nil
+			}
 
-func (p *Parser) parseGroupedExpression() ast.Expression {
-	p.next()
-	exp := p.parseExpression(LOWEST)
+			pattern.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Val}
 
-	if !p.expectPeek(jlang.RPAREN) {
-		return nil
-	}
+			if !p.peekTokenIs(jlang.RBRACKET) {
+				p.Error(fmt.Sprintf("rest element %s must be last, line %d, col %d",
+					pattern.Rest.Value, p.curToken.Line+1, p.curToken.Column+1))
+				return nil
+			}
+			break
+		}
 
-	return exp
-}
+		element := p.parsePattern()
+		if element == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, element)
 
-func (p *Parser) parsePrefixExpression() ast.Expression {
-	expression := &ast.PrefixExpression{
-		Token:    p.curToken,
-		Operator: p.curToken.Val,
+		if !p.peekTokenIs(jlang.RBRACKET) && !p.expectPeek(jlang.COMMA) {
+			return nil
+		}
 	}
 
 	p.next()
-	expression.RightExpression = p.parseExpression(PREFIX)
-
-	return expression
+	return pattern
 }
 
-func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
-	exp := &ast.InfixExpression{
-		Token:          p.curToken,
-		Operator:       p.curToken.Val,
-		LeftExpression: left,
+func (p *Parser) parseHashPattern() ast.Pattern {
+	pattern := &ast.HashPattern{Token: p.curToken, Entries: []*ast.HashPatternEntry{}}
+
+	for !p.peekTokenIs(jlang.RBRACE) {
+		if !p.expectPeek(jlang.IDENT) {
+			return nil
+		}
+
+		entry := &ast.HashPatternEntry{
+			Key: &ast.Identifier{Token: p.curToken, Value: p.curToken.Val},
+		}
+
+		if p.peekTokenIs(jlang.COLON) {
+			p.next()
+			p.next()
+
+			entry.Value = p.parsePattern()
+			if entry.Value == nil {
+				return nil
+			}
+		} else {
+			entry.Value = entry.Key
+		}
+
+		pattern.Entries = append(pattern.Entries, entry)
+
+		if !p.peekTokenIs(jlang.RBRACE) && !p.expectPeek(jlang.COMMA) {
+			return nil
+		}
 	}
 
-	precedence := p.curPrecedence()
 	p.next()
-	exp.RightExpression = p.parseExpression(precedence)
-
-	return exp
+	return pattern
 }
 
-func (p *Parser) parseIfExpression() ast.Expression {
-	exp := &ast.IFExpression{
-		Token: p.curToken,
+// checkDuplicateBindings reports names bound more than once by pattern.
+func (p *Parser) checkDuplicateBindings(pattern ast.Pattern) {
+	seen := make(map[string]bool)
+
+	for _, name := range ast.PatternNames(pattern) {
+		if seen[name] {
+			p.Error(fmt.Sprintf("duplicate binding %s in pattern %s, line %d, col %d",
+				name, pattern.String(), p.curToken.Line+1, p.curToken.Column+1))
+		}
+		seen[name] = true
 	}
+}
 
-	if !p.expectPeek(jlang.LPAREN) {
-		return nil
+// checkIrrefutable reports patterns which may fail to match, since
+// binding in let statement or comprehension has no alternative to fall back to.
+func (p *Parser) checkIrrefutable(pattern ast.Pattern, context string) {
+	switch pt := pattern.(type) {
+	case *ast.LiteralPattern:
+		p.Error(fmt.Sprintf("literal pattern %s is not allowed in %s, line %d, col %d",
+			pt.String(), context, pt.Token.Line+1, pt.Token.Column+1))
+	case *ast.VariantPattern:
+		p.Error(fmt.Sprintf("variant pattern %s is not allowed in %s, line %d, col %d",
+			pt.String(), context, pt.Token.Line+1, pt.Token.Column+1))
+	case *ast.ArrayPattern:
+		for _, e := range pt.Elements {
+			p.checkIrrefutable(e, context)
+		}
+	case *ast.HashPattern:
+		for _, e := range pt.Entries {
+			p.checkIrrefutable(e.Value, context)
+		}
 	}
+}
 
-	p.next()
-	exp.Condition = p.parseExpression(LOWEST)
+// parseStructStatement parses <struct> <name> { <ident>, <ident>, ... }
+func (p *Parser) parseStructStatement() ast.Statement {
+	stmt := &ast.StructStatement{Token: p.curToken, Fields: []*ast.Identifier{}}
 
-	if !p.expectPeek(jlang.RPAREN) {
+	if !p.expectPeek(jlang.IDENT) {
 		return nil
 	}
 
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Val}
+
 	if !p.expectPeek(jlang.LBRACE) {
 		return nil
 	}
 
-	exp.Consequence = p.parseBlockStatement()
+	for !p.peekTokenIs(jlang.RBRACE) {
+		if !p.expectPeek(jlang.IDENT) {
+			return nil
+		}
 
-	if p.peekTokenIs(jlang.ELSE) {
-		p.next()
+		field := &ast.Identifier{Token: p.curToken, Value: p.curToken.Val}
+		if stmt.HasField(field.Value) {
+			p.Error(fmt.Sprintf("duplicate field %s of struct %s, line %d, col %d",
+				field.Value, stmt.Name.Value, p.curToken.Line+1, p.curToken.Column+1))
+		}
+		stmt.Fields = append(stmt.Fields, field)
 
-		if !p.expectPeek(jlang.LBRACE) {
+		if !p.peekTokenIs(jlang.RBRACE) && !p.expectPeek(jlang.COMMA) {
 			return nil
 		}
+	}
 
-		exp.Alternative = p.parseBlockStatement()
+	p.next()
+	p.declare(stmt.Name.Value, &binding{structDecl: stmt})
+
+	if p.peekTokenIs(jlang.SEMICOLON) {
+		p.next()
 	}
 
-	return exp
+	return stmt
 }
 
-func (p *Parser) parseFunctionExpression() ast.Expression {
-	functionExp := &ast.FunctionExpression{
-		Token: p.curToken,
-	}
+// parseEnumStatement parses <enum> <name> { <ident>, <ident>(<ident>, ...), ... }
+func (p *Parser) parseEnumStatement() ast.Statement {
+	stmt := &ast.EnumStatement{Token: p.curToken, Variants: []*ast.EnumVariant{}}
 
-	if !p.expectPeek(jlang.LPAREN) {
+	if !p.expectPeek(jlang.IDENT) {
 		return nil
 	}
 
-	functionExp.Args = p.parseFunctionParameters()
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Val}
 
 	if !p.expectPeek(jlang.LBRACE) {
 		return nil
 	}
 
-	functionExp.Body = p.parseBlockStatement()
+	for !p.peekTokenIs(jlang.RBRACE) {
+		if !p.expectPeek(jlang.IDENT) {
+			return nil
+		}
 
-	return functionExp
-}
+		variant := &ast.EnumVariant{
+			Name:   &ast.Identifier{Token: p.curToken, Value: p.curToken.Val},
+			Fields: []*ast.Identifier{},
+		}
 
-func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
-	exp := &ast.CallExpression{Token: p.curToken, Function: function}
-	exp.Args = p.parseCallArguments()
+		if stmt.Variant(variant.Name.Value) != nil {
+			p.Error(fmt.Sprintf("duplicate variant %s of enum %s, line %d, col %d",
+				variant.Name.Value, stmt.Name.Value, p.curToken.Line+1, p.curToken.Column+1))
+		}
 
-	return exp
-}
+		if p.peekTokenIs(jlang.LPAREN) {
+			p.next()
 
-func (p *Parser) parseCallArguments() []ast.Expression {
-	args := []ast.Expression{}
+			// fields are parsed like parameters, which are declared in a scope of their own
+			p.openScope()
+			fn := &ast.FunctionExpression{}
+			ok := p.parseFunctionParameters(fn)
+			p.closeScope()
 
-	if p.peekTokenIs(jlang.RPAREN) {
-		p.next()
-		return args
+			if !ok {
+				return nil
+			}
+
+			if len(fn.Defaults) != 0 || fn.Rest != nil {
+				p.Error(fmt.Sprintf("fields of variant %s must be plain identifiers, line %d, col %d",
+					variant.Name.Value, variant.Name.Token.Line+1, variant.Name.Token.Column+1))
+			}
+
+			variant.Fields = fn.Args
+		}
+
+		stmt.Variants = append(stmt.Variants, variant)
+
+		if !p.peekTokenIs(jlang.RBRACE) && !p.expectPeek(jlang.COMMA) {
+			return nil
+		}
 	}
 
 	p.next()
-	args = append(args, p.parseExpression(LOWEST))
 
-	for p.peekTokenIs(jlang.COMMA) {
-		p.next()
-		p.next()
-		args = append(args, p.parseExpression(LOWEST))
+	p.declare(stmt.Name.Value, &binding{})
+	for _, v := range stmt.Variants {
+		p.declare(v.Name.Value, &binding{variant: v, enumDecl: stmt})
 	}
 
-	if !p.expectPeek(jlang.RPAREN) {
-		return nil
+	if p.peekTokenIs(jlang.SEMICOLON) {
+		p.next()
 	}
 
-	return args
+	return stmt
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	identifiers := []*ast.Identifier{}
+func (p *Parser) parseFunctionStatement() ast.Statement {
+	stmt := &ast.FunctionStatement{Token: p.curToken}
 
-	for !p.peekTokenIs(jlang.RPAREN) {
+	generator := false
+	if p.peekTokenIs(jlang.ASTERISK) {
 		p.next()
+		generator = true
 
-		ident := &ast.Identifier{
-			Token: p.curToken,
-			Value: p.curToken.Val,
-		}
-		identifiers = append(identifiers, ident)
-
-		for p.peekTokenIs(jlang.COMMA) {
-			p.next()
-			p.next()
-			ident := &ast.Identifier{
-				Token: p.curToken,
-				Value: p.curToken.Val,
-			}
-			identifiers = append(identifiers, ident)
+		// anonymous generator used as expression statement
+		if p.peekTokenIs(jlang.LPAREN) {
+			return p.parseGeneratorExpressionStatement(stmt.Token)
 		}
 	}
 
-	p.next()
-	return identifiers
-}
+	if !p.expectPeek(jlang.IDENT) {
+		return nil
+	}
 
-func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Val}
+	p.declare(stmt.Name.Value, &binding{})
+
+	fn := p.parseFunctionLiteral(stmt.Token, generator)
+	if fn == nil {
+		return nil
+	}
+
+	fn.Name = stmt.Name.Value
+	stmt.Function = fn
+
+	if p.peekTokenIs(jlang.SEMICOLON) {
+		p.next()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseGeneratorExpressionStatement(token jlang.Token) ast.Statement {
+	stmt := &ast.ExpressionStatement{Token: token}
+
+	fn := p.parseFunctionLiteral(token, true)
+	if fn == nil {
+		return nil
+	}
+
+	stmt.Expression = p.parseOperators(fn, LOWEST)
+
+	if p.peekTokenIs(jlang.SEMICOLON) {
+		p.next()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseReturnStatement() ast.Statement {
+
+	// Define statement
+	stmt := &ast.ReturnStatement{Token: p.curToken}
+
+	// Check next
+	p.next()
+	stmt.ReturnValue = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(jlang.SEMICOLON) {
+		p.next()
+	}
+
+	return stmt
+}
+
+// parseImportStatement parses <import> <string> [<as> <ident>]
+func (p *Parser) parseImportStatement() ast.Statement {
+	stmt := &ast.ImportStatement{Token: p.curToken}
+
+	if len(p.scopes) > 1 {
+		p.Error(fmt.Sprintf("import must be at top level, line %d, col %d",
+			p.curToken.Line+1, p.curToken.Column+1))
+	}
+
+	if !p.expectPeek(jlang.STRING) {
+		return nil
+	}
+
+	path, ok := p.parseStringLiteral().(*ast.StringLiteral)
+	if !ok {
+		return nil
+	}
+
+	if path.Value == "" {
+		p.Error(fmt.Sprintf("import path is empty, line %d, col %d",
+			p.curToken.Line+1, p.curToken.Column+1))
+		return nil
+	}
+
+	stmt.Path = path
+
+	if p.peekTokenIs(jlang.AS) {
+		p.next()
+
+		if !p.expectPeek(jlang.IDENT) {
+			return nil
+		}
+
+		stmt.Alias = &ast.Identifier{Token: p.curToken, Value: p.curToken.Val}
+	}
+
+	p.declare(stmt.Name(), &binding{})
+
+	if p.peekTokenIs(jlang.SEMICOLON) {
+		p.next()
+	}
+
+	return stmt
+}
+
+// parseExportStatement parses <export> <declaration>
+func (p *Parser) parseExportStatement() ast.Statement {
+	stmt := &ast.ExportStatement{Token: p.curToken}
+
+	if len(p.scopes) > 1 {
+		p.Error(fmt.Sprintf("export must be at top level, line %d, col %d",
+			p.curToken.Line+1, p.curToken.Column+1))
+	}
+
+	p.next()
+
+	switch p.curToken.Type {
+	case jlang.LET, jlang.STRUCT, jlang.ENUM:
+		stmt.Statement = p.parseStatement()
+	case jlang.FUNCTION:
+		if !p.peekTokenIs(jlang.IDENT) && !p.peekTokenIs(jlang.ASTERISK) {
+			p.peekError(jlang.IDENT)
+			return nil
+		}
+		stmt.Statement = p.parseStatement()
+	default:
+		p.Error(fmt.Sprintf("expected declaration after export, got %s instead, line %d, col %d",
+			p.curToken.Type, p.curToken.Line+1, p.curToken.Column+1))
+		return nil
+	}
+
+	if stmt.Statement == nil {
+		return nil
+	}
+
+	// anonymous generator is parsed as expression statement
+	if _, ok := stmt.Statement.(*ast.ExpressionStatement); ok {
+		p.Error(fmt.Sprintf("exported function needs a name, line %d, col %d",
+			stmt.Token.Line+1, stmt.Token.Column+1))
+		return nil
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseThrowStatement() ast.Statement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+
+	p.next()
+	stmt.Value = p.parseExpression(LOWEST)
+	if stmt.Value == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(jlang.SEMICOLON) {
+		p.next()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseTryStatement() ast.Statement {
+	stmt := &ast.TryStatement{Token: p.curToken}
+
+	if !p.expectPeek(jlang.LBRACE) {
+		return nil
+	}
+
+	stmt.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(jlang.CATCH) {
+		p.next()
+
+		if !p.expectPeek(jlang.LPAREN) {
+			return nil
+		}
+
+		if !p.expectPeek(jlang.IDENT) {
+			return nil
+		}
+
+		stmt.CatchParam = &ast.Identifier{Token: p.curToken, Value: p.curToken.Val}
+
+		if !p.expectPeek(jlang.RPAREN) {
+			return nil
+		}
+
+		if !p.expectPeek(jlang.LBRACE) {
+			return nil
+		}
+
+		// catch parameter is only visible in catch block
+		p.openScope()
+		p.declare(stmt.CatchParam.Value, &binding{})
+		stmt.Catch = p.parseBlockStatement()
+		p.closeScope()
+	}
+
+	if p.peekTokenIs(jlang.FINALLY) {
+		p.next()
+
+		if !p.expectPeek(jlang.LBRACE) {
+			return nil
+		}
+
+		stmt.Finally = p.parseBlockStatement()
+	}
+
+	if stmt.Catch == nil && stmt.Finally == nil {
+		p.Error(fmt.Sprintf("try statement needs catch or finally block, line %d, col %d",
+			stmt.Token.Line+1, stmt.Token.Column+1))
+		return nil
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
+	stmt := &ast.ExpressionStatement{Token: p.curToken}
+
+	stmt.Expression = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(jlang.SEMICOLON) {
+		p.next()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseExpression(precedence int) ast.Expression {
+
+	prefix := p.prefixParsefns[p.curToken.Type]
+	if prefix == nil {
+		p.Error(fmt.Sprintf("no prefix parse function for %s found", p.curToken.Type))
+		return nil
+	}
+
+	return p.parseOperators(prefix(), precedence)
+}
+
+// parseOperators parses postfix and infix operators following left expression
+// while they bind tighter than given precedence.
+func (p *Parser) parseOperators(leftExp ast.Expression, precedence int) ast.Expression {
+	for !p.peekTokenIs(jlang.SEMICOLON) && precedence < p.peekPrecedence() {
+		// postfix operator applies to left expression and does not take right expression
+		if postfix := p.postfixParsefns[p.nextToken.Type]; postfix != nil {
+			p.next()
+			leftExp = postfix(leftExp)
+			continue
+		}
+
+		infix := p.infixParsefns[p.nextToken.Type]
+		if infix == nil {
+			return leftExp
+		}
+
+		p.next()
+		leftExp = infix(leftExp)
+
+		// malformed right side was reported, operators following it are not parsed
+		if leftExp == nil {
+			return nil
+		}
+	}
+
+	return leftExp
+}
+
+// parseGroupedExpression parses (<expression>). Parentheses around an optional
+// chain are kept as ChainExpression since they end its short-circuit.
+func (p *Parser) parseGroupedExpression() ast.Expression {
+	token := p.curToken
+	p.next()
+	exp := p.parseExpression(LOWEST)
+
+	if !p.expectPeek(jlang.RPAREN) {
+		return nil
+	}
+
+	if ast.IsOptionalChain(exp) {
+		return &ast.ChainExpression{Token: token, Chain: exp}
+	}
+
+	return exp
+}
+
+// parseArrayLiteral parses [<expression>, ...] or list comprehension
+// [<expression> <for> <pattern> <in> <expression> <if> <condition>].
+// Both start with an expression, and comprehension is chosen when
+// the first expression is followed by <for>.
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	token := p.curToken
+	elements := []ast.Expression{}
+
+	if p.peekTokenIs(jlang.RBRACKET) {
+		p.next()
+		return &ast.ArrayLiteral{Token: token, Elements: elements}
+	}
+
+	p.next()
+	p.deferFieldChecks()
+	first := p.parseExpression(LOWEST)
+	deferred := p.resumeFieldChecks()
+
+	if first == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(jlang.FOR) {
+		exp := &ast.ListComprehension{Token: token, Element: first}
+
+		exp.Clause = p.parseComprehensionClause(deferred)
+		if exp.Clause == nil || !p.expectPeek(jlang.RBRACKET) {
+			return nil
+		}
+
+		return exp
+	}
+
+	p.checkFieldAccesses(deferred)
+	elements = append(elements, first)
+	for p.peekTokenIs(jlang.COMMA) {
+		p.next()
+
+		// trailing comma
+		if p.peekTokenIs(jlang.RBRACKET) {
+			break
+		}
+
+		p.next()
+		element := p.parseExpression(LOWEST)
+		if element == nil {
+			return nil
+		}
+		elements = append(elements, element)
+	}
+
+	if !p.expectPeek(jlang.RBRACKET) {
+		return nil
+	}
+
+	return &ast.ArrayLiteral{Token: token, Elements: elements}
+}
+
+// parseHashLiteral parses {<expression>: <expression>, ...} or hash comprehension
+// {<expression>: <expression> <for> <pattern> <in> <expression> <if> <condition>}.
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.curToken, Pairs: []*ast.HashPair{}}
+
+	for !p.peekTokenIs(jlang.RBRACE) {
+		// first pair may be key and value of a comprehension
+		first := len(hash.Pairs) == 0
+		if first {
+			p.deferFieldChecks()
+		}
+
+		key, value, ok := p.parseHashPair()
+
+		deferred := []*ast.SelectorExpression{}
+		if first {
+			deferred = p.resumeFieldChecks()
+		}
+
+		if !ok {
+			return nil
+		}
+
+		if first && p.peekTokenIs(jlang.FOR) {
+			exp := &ast.HashComprehension{Token: hash.Token, Key: key, Value: value}
+
+			exp.Clause = p.parseComprehensionClause(deferred)
+			if exp.Clause == nil || !p.expectPeek(jlang.RBRACE) {
+				return nil
+			}
+
+			return exp
+		}
+
+		p.checkFieldAccesses(deferred)
+		hash.Pairs = append(hash.Pairs, &ast.HashPair{Key: key, Value: value})
+
+		if !p.peekTokenIs(jlang.RBRACE) && !p.expectPeek(jlang.COMMA) {
+			return nil
+		}
+	}
+
+	p.next()
+	return hash
+}
+
+// parseHashPair parses <expression>: <expression> starting at next token.
+func (p *Parser) parseHashPair() (ast.Expression, ast.Expression, bool) {
+	p.next()
+	key := p.parseExpression(LOWEST)
+
+	if key == nil || !p.expectPeek(jlang.COLON) {
+		return nil, nil, false
+	}
+
+	p.next()
+	value := p.parseExpression(LOWEST)
+
+	return key, value, value != nil
+}
+
+// parseComprehensionClause parses <for> <pattern> <in> <expression> [<if> <condition>]
+// Next token should be <for>. Names bound by pattern are in scope of the condition and
+// of the element, whose field accesses deferred while parsing it are checked here.
+func (p *Parser) parseComprehensionClause(element []*ast.SelectorExpression) *ast.ComprehensionClause {
+	p.next()
+	clause := &ast.ComprehensionClause{Token: p.curToken}
+
+	p.next()
+	clause.Pattern = p.parsePattern()
+	if clause.Pattern == nil {
+		return nil
+	}
+
+	p.checkDuplicateBindings(clause.Pattern)
+	p.checkIrrefutable(clause.Pattern, "comprehension")
+
+	if !p.expectPeek(jlang.IN) {
+		return nil
+	}
+
+	p.next()
+	clause.Iterable = p.parseExpression(LOWEST)
+	if clause.Iterable == nil {
+		return nil
+	}
+
+	p.openScope()
+	defer p.closeScope()
+	p.declarePattern(clause.Pattern)
+
+	if p.peekTokenIs(jlang.IF) {
+		p.next()
+		p.next()
+		clause.Condition = p.parseExpression(LOWEST)
+		if clause.Condition == nil {
+			return nil
+		}
+	}
+
+	p.checkFieldAccesses(element)
+
+	return clause
+}
+
+func (p *Parser) parsePrefixExpression() ast.Expression {
+	expression := &ast.PrefixExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Val,
+	}
+
+	p.next()
+	expression.RightExpression = p.parseExpression(PREFIX)
+
+	return expression
+}
+
+func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
+	exp := &ast.InfixExpression{
+		Token:          p.curToken,
+		Operator:       p.curToken.Val,
+		LeftExpression: left,
+	}
+
+	precedence := p.curPrecedence()
+	p.next()
+	exp.RightExpression = p.parseExpression(precedence)
+
+	return exp
+}
+
+func (p *Parser) parsePipeExpression(left ast.Expression) ast.Expression {
+	exp := &ast.PipeExpression{
+		Token:          p.curToken,
+		LeftExpression: left,
+	}
+
+	precedence := p.curPrecedence()
+	p.next()
+	exp.RightExpression = p.parseExpression(precedence)
+
+	right := exp.RightExpression
+	if chain, ok := right.(*ast.ChainExpression); ok {
+		right = chain.Chain
+	}
+
+	switch right.(type) {
+	case *ast.CallExpression, *ast.Identifier, *ast.FunctionExpression, *ast.SelectorExpression:
+	case nil:
+		return nil
+	default:
+		p.Error(fmt.Sprintf("right side of |> must be a function or a call, got %s, line %d, col %d",
+			exp.RightExpression, exp.Token.Line+1, exp.Token.Column+1))
+		return nil
+	}
+
+	return exp
+}
+
+func (p *Parser) parseSelectorExpression(object ast.Expression) ast.Expression {
+	exp := &ast.SelectorExpression{Token: p.curToken, Object: object}
+
+	if !p.expectPeek(jlang.IDENT) {
+		return nil
+	}
+
+	exp.Selector = &ast.Identifier{Token: p.curToken, Value: p.curToken.Val}
+	p.checkFieldAccess(exp)
+
+	return exp
+}
+
+// parseOptionalChain parses <expression>?.<ident>, <expression>?.[<index>]
+// and <expression>?.(<arguments>)
+func (p *Parser) parseOptionalChain(left ast.Expression) ast.Expression {
+	switch p.nextToken.Type {
+	case jlang.LBRACKET:
+		p.next()
+		switch exp := p.parseIndexExpression(left).(type) {
+		case *ast.IndexExpression:
+			exp.Optional = true
+			return exp
+		case *ast.SliceExpression:
+			exp.Optional = true
+			return exp
+		}
+		return nil
+	case jlang.LPAREN:
+		p.next()
+		exp := p.parseCallExpression(left).(*ast.CallExpression)
+		exp.Optional = true
+		return exp
+	default:
+		exp, ok := p.parseSelectorExpression(left).(*ast.SelectorExpression)
+		if !ok {
+			return nil
+		}
+		exp.Optional = true
+		return exp
+	}
+}
+
+// parsePropagateExpression parses <expression>?
+// It returns early from the enclosing function when expression is an error value.
+func (p *Parser) parsePropagateExpression(left ast.Expression) ast.Expression {
+	exp := &ast.PostfixExpression{
+		Token:          p.curToken,
+		Operator:       p.curToken.Val,
+		LeftExpression: left,
+	}
+
+	if len(p.functions) == 0 {
+		p.Error(fmt.Sprintf("? operator used outside of function, line %d, col %d",
+			p.curToken.Line+1, p.curToken.Column+1))
+	}
+
+	return exp
+}
+
+// parseYieldExpression parses <yield> [<expression>]
+// It makes the innermost enclosing function a generator.
+func (p *Parser) parseYieldExpression() ast.Expression {
+	exp := &ast.YieldExpression{Token: p.curToken}
+
+	if len(p.functions) == 0 {
+		p.Error(fmt.Sprintf("yield used outside of function, line %d, col %d",
+			p.curToken.Line+1, p.curToken.Column+1))
+	} else {
+		p.functions[len(p.functions)-1].Generator = true
+	}
+
+	switch p.nextToken.Type {
+	case jlang.SEMICOLON, jlang.RBRACE, jlang.RPAREN, jlang.RBRACKET, jlang.COMMA, jlang.EOF:
+		return exp
+	}
+
+	p.next()
+	exp.Value = p.parseExpression(LOWEST)
+
+	return exp
+}
+
+func (p *Parser) parseRangeExpression(left ast.Expression) ast.Expression {
+	exp := &ast.RangeExpression{
+		Token:     p.curToken,
+		Start:     left,
+		Inclusive: p.curTokenIs(jlang.RANGE_INCLUSIVE),
+	}
+
+	precedence := p.curPrecedence()
+	p.next()
+	exp.End = p.parseExpression(precedence)
+	if exp.End == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(jlang.RANGE) || p.peekTokenIs(jlang.RANGE_INCLUSIVE) {
+		p.Error(fmt.Sprintf("range of range is not allowed, use parentheses, line %d, col %d",
+			p.nextToken.Line+1, p.nextToken.Column+1))
+		return nil
+	}
+
+	return exp
+}
+
+// parseIndexExpression parses <expression>[<index>] or <expression>[<low>:<high>]
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	token := p.curToken
+	p.next()
+
+	var low ast.Expression
+	if !p.curTokenIs(jlang.COLON) {
+		low = p.parseExpression(LOWEST)
+		if low == nil {
+			return nil
+		}
+
+		if !p.peekTokenIs(jlang.COLON) {
+			if !p.expectPeek(jlang.RBRACKET) {
+				return nil
+			}
+			return &ast.IndexExpression{Token: token, Left: left, Index: low}
+		}
+		p.next()
+	}
+
+	slice := &ast.SliceExpression{Token: token, Left: left, Low: low}
+
+	if !p.peekTokenIs(jlang.RBRACKET) {
+		p.next()
+		slice.High = p.parseExpression(LOWEST)
+		if slice.High == nil {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(jlang.RBRACKET) {
+		return nil
+	}
+
+	return slice
+}
+
+func (p *Parser) parseIfExpression() ast.Expression {
+	exp := &ast.IFExpression{
+		Token: p.curToken,
+	}
+
+	if !p.expectPeek(jlang.LPAREN) {
+		return nil
+	}
+
+	p.next()
+	exp.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(jlang.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(jlang.LBRACE) {
+		return nil
+	}
+
+	exp.Consequence = p.parseBlockStatement()
+
+	if p.peekTokenIs(jlang.ELSE) {
+		p.next()
+
+		if !p.expectPeek(jlang.LBRACE) {
+			return nil
+		}
+
+		exp.Alternative = p.parseBlockStatement()
+	}
+
+	return exp
+}
+
+func (p *Parser) parseMatchExpression() ast.Expression {
+	exp := &ast.MatchExpression{
+		Token: p.curToken,
+		Arms:  []*ast.MatchArm{},
+	}
+
+	if !p.expectPeek(jlang.LPAREN) {
+		return nil
+	}
+
+	p.next()
+	exp.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(jlang.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(jlang.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(jlang.RBRACE) {
+		p.next()
+
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		exp.Arms = append(exp.Arms, arm)
+
+		if p.peekTokenIs(jlang.COMMA) {
+			p.next()
+		}
+	}
+
+	p.next()
+
+	if len(exp.Arms) == 0 {
+		p.Error(fmt.Sprintf("match expression has no arms, line %d, col %d",
+			exp.Token.Line+1, exp.Token.Column+1))
+		return nil
+	}
+
+	p.checkMatchArms(exp)
+
+	return exp
+}
+
+// parseMatchArm parses <pattern> [<if> <guard>] => <body>
+// Body is a block statement or a single expression.
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{Token: p.curToken}
+
+	p.openScope()
+	defer p.closeScope()
+
+	arm.Pattern = p.parsePattern()
+	if arm.Pattern == nil {
+		return nil
+	}
+
+	p.checkDuplicateBindings(arm.Pattern)
+	p.declarePattern(arm.Pattern)
+
+	if p.peekTokenIs(jlang.IF) {
+		p.next()
+		p.next()
+		arm.Guard = p.parseExpression(LOWEST)
+	}
+
+	if !p.expectPeek(jlang.ARROW) {
+		return nil
+	}
+
+	if p.peekTokenIs(jlang.LBRACE) {
+		p.next()
+		arm.Body = p.parseBlockStatement()
+		return arm
+	}
+
+	p.next()
+	stmt := &ast.ExpressionStatement{Token: p.curToken}
+	stmt.Expression = p.parseExpression(LOWEST)
+	arm.Body = &ast.BlockStatement{
+		Token:      stmt.Token,
+		Statements: []ast.Statement{stmt},
+	}
+
+	return arm
+}
+
+// checkMatchArms warns about arms which can never be selected and
+// match expressions which may not handle every value.
+// Match on enum variants is exhaustive when every variant of the enum is covered.
+func (p *Parser) checkMatchArms(exp *ast.MatchExpression) {
+	exhaustive := false
+	literals := make(map[string]bool)
+	variants := make(map[string]bool)
+
+	var enumDecl *ast.EnumStatement
+
+	for _, arm := range exp.Arms {
+		if exhaustive {
+			p.Warning(fmt.Sprintf("unreachable match arm %s, line %d, col %d",
+				arm.Pattern.String(), arm.Token.Line+1, arm.Token.Column+1))
+			continue
+		}
+
+		switch pattern := arm.Pattern.(type) {
+		case *ast.Identifier, *ast.WildcardPattern:
+			exhaustive = arm.Guard == nil
+		case *ast.LiteralPattern:
+			if literals[pattern.String()] {
+				p.Warning(fmt.Sprintf("unreachable match arm %s, line %d, col %d",
+					arm.Pattern.String(), arm.Token.Line+1, arm.Token.Column+1))
+				continue
+			}
+
+			if arm.Guard == nil {
+				literals[pattern.String()] = true
+			}
+		case *ast.VariantPattern:
+			if variants[pattern.Name.Value] {
+				p.Warning(fmt.Sprintf("unreachable match arm %s, line %d, col %d",
+					arm.Pattern.String(), arm.Token.Line+1, arm.Token.Column+1))
+				continue
+			}
+
+			if _, e := p.lookupVariant(pattern.Name.Value); enumDecl == nil {
+				enumDecl = e
+			}
+
+			if arm.Guard == nil && isIrrefutable(pattern.Args) {
+				variants[pattern.Name.Value] = true
+			}
+		}
+
+		// true and false cover every boolean
+		if literals["true"] && literals["false"] {
+			exhaustive = true
+		}
+
+		if enumDecl != nil && len(missingVariants(enumDecl, variants)) == 0 {
+			exhaustive = true
+		}
+	}
+
+	if exhaustive {
+		return
+	}
+
+	if enumDecl != nil {
+		p.Warning(fmt.Sprintf("non-exhaustive match expression, missing variants %s of enum %s, line %d, col %d",
+			strings.Join(missingVariants(enumDecl, variants), ", "), enumDecl.Name.Value,
+			exp.Token.Line+1, exp.Token.Column+1))
+		return
+	}
+
+	p.Warning(fmt.Sprintf("non-exhaustive match expression, add a wildcard arm, line %d, col %d",
+		exp.Token.Line+1, exp.Token.Column+1))
+}
+
+// isIrrefutable returns whether every pattern matches any value.
+func isIrrefutable(patterns []ast.Pattern) bool {
+	for _, pattern := range patterns {
+		switch pattern.(type) {
+		case *ast.Identifier, *ast.WildcardPattern:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// missingVariants returns names of variants of enum which are not covered.
+func missingVariants(enumDecl *ast.EnumStatement, covered map[string]bool) []string {
+	missing := []string{}
+	for _, v := range enumDecl.Variants {
+		if !covered[v.Name.Value] {
+			missing = append(missing, v.Name.Value)
+		}
+	}
+
+	return missing
+}
+
+func (p *Parser) parseFunctionExpression() ast.Expression {
+	token := p.curToken
+
+	generator := false
+	if p.peekTokenIs(jlang.ASTERISK) {
+		p.next()
+		generator = true
+	}
+
+	functionExp := p.parseFunctionLiteral(token, generator)
+	if functionExp == nil {
+		return nil
+	}
+
+	return functionExp
+}
+
+// parseFunctionLiteral parses <parameters> <blockstatement> of function.
+// Current token should be the token right before the parameters.
+func (p *Parser) parseFunctionLiteral(token jlang.Token, generator bool) *ast.FunctionExpression {
+	functionExp := &ast.FunctionExpression{
+		Token:     token,
+		Generator: generator,
+	}
+
+	if !p.expectPeek(jlang.LPAREN) {
+		return nil
+	}
+
+	p.openScope()
+	defer p.closeScope()
+
+	p.functions = append(p.functions, functionExp)
+	defer func() { p.functions = p.functions[:len(p.functions)-1] }()
+
+	if !p.parseFunctionParameters(functionExp) {
+		return nil
+	}
+
+	if !p.expectPeek(jlang.LBRACE) {
+		return nil
+	}
+
+	functionExp.Body = p.parseBlockStatement()
+
+	return functionExp
+}
+
+func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	exp := &ast.CallExpression{Token: p.curToken, Function: function}
+	exp.Args = p.parseCallArguments()
+
+	if exp.Args != nil {
+		p.checkConstructorCall(exp)
+	}
+
+	return exp
+}
+
+// parseCallArguments parses (<expression>, ...<expression>, <ident>: <expression>)
+// Keyword arguments come after positional and spread arguments.
+func (p *Parser) parseCallArguments() []ast.Expression {
+	args := []ast.Expression{}
+
+	if p.peekTokenIs(jlang.RPAREN) {
+		p.next()
+		return args
+	}
+
+	keywords := make(map[string]bool)
+
+	for {
+		p.next()
+		arg := p.parseCallArgument()
+		if arg == nil {
+			return nil
+		}
+
+		if kw, ok := arg.(*ast.KeywordArgument); ok {
+			if keywords[kw.Name.Value] {
+				p.Error(fmt.Sprintf("duplicate keyword argument %s, line %d, col %d",
+					kw.Name.Value, kw.Token.Line+1, kw.Token.Column+1))
+			}
+			keywords[kw.Name.Value] = true
+		} else if len(keywords) != 0 {
+			p.Error(fmt.Sprintf("positional argument follows keyword argument, line %d, col %d",
+				p.curToken.Line+1, p.curToken.Column+1))
+		}
+
+		args = append(args, arg)
+
+		if !p.peekTokenIs(jlang.COMMA) {
+			break
+		}
+		p.next()
+	}
+
+	if !p.expectPeek(jlang.RPAREN) {
+		return nil
+	}
+
+	return args
+}
+
+func (p *Parser) parseCallArgument() ast.Expression {
+	switch {
+	case p.curTokenIs(jlang.ELLIPSIS):
+		exp := &ast.SpreadExpression{Token: p.curToken}
+		p.next()
+		exp.Value = p.parseExpression(LOWEST)
+		if exp.Value == nil {
+			return nil
+		}
+		return exp
+	case p.curTokenIs(jlang.IDENT) && p.peekTokenIs(jlang.COLON):
+		exp := &ast.KeywordArgument{
+			Token: p.curToken,
+			Name:  &ast.Identifier{Token: p.curToken, Value: p.curToken.Val},
+		}
+		p.next()
+		p.next()
+		exp.Value = p.parseExpression(LOWEST)
+		if exp.Value == nil {
+			return nil
+		}
+		return exp
+	default:
+		return p.parseExpression(LOWEST)
+	}
+}
+
+// parseFunctionParameters parses (<ident>, <ident> = <expression>, ..., ...<ident>)
+// into Args, Defaults and Rest of function.
+// Parameters should be identifiers without duplicated name and trailing comma.
+// Parameters with default value come after required parameters and
+// rest parameter comes last. Each parameter is declared in current scope
+// once parsed, so defaults see parameters before them.
+func (p *Parser) parseFunctionParameters(fn *ast.FunctionExpression) bool {
+	fn.Args = []*ast.Identifier{}
+	fn.Defaults = make(map[string]ast.Expression)
+
+	if p.peekTokenIs(jlang.RPAREN) {
+		p.next()
+		return true
+	}
+
+	seen := make(map[string]bool)
+
+	for {
+		p.next()
+
+		rest := p.curTokenIs(jlang.ELLIPSIS)
+		if rest {
+			p.next()
+		}
+
+		if !p.curTokenIs(jlang.IDENT) {
+			p.Error(fmt.Sprintf("expected parameter name, got %s instead, line %d, col %d",
+				p.curToken.Type, p.curToken.Line+1, p.curToken.Column+1))
+			return false
+		}
+
+		ident := &ast.Identifier{
+			Token: p.curToken,
+			Value: p.curToken.Val,
+		}
+
+		if seen[ident.Value] {
+			p.Error(fmt.Sprintf("duplicate parameter %s, line %d, col %d",
+				ident.Value, p.curToken.Line+1, p.curToken.Column+1))
+		}
+
+		seen[ident.Value] = true
+
+		if rest {
+			fn.Rest = ident
+			p.declare(ident.Value, &binding{})
+
+			if !p.peekTokenIs(jlang.RPAREN) {
+				p.Error(fmt.Sprintf("rest parameter %s must be last, line %d, col %d",
+					ident.Value, p.curToken.Line+1, p.curToken.Column+1))
+				return false
+			}
+			break
+		}
+
+		if p.peekTokenIs(jlang.ASSIGN) {
+			p.next()
+			p.next()
+
+			value := p.parseExpression(LOWEST)
+			if value == nil {
+				return false
+			}
+			fn.Defaults[ident.Value] = value
+		} else if len(fn.Defaults) != 0 {
+			p.Error(fmt.Sprintf("parameter %s without default follows parameter with default, line %d, col %d",
+				ident.Value, ident.Token.Line+1, ident.Token.Column+1))
+		}
+
+		fn.Args = append(fn.Args, ident)
+		p.declare(ident.Value, &binding{})
+
+		if !p.peekTokenIs(jlang.COMMA) {
+			break
+		}
+
+		p.next()
+
+		if p.peekTokenIs(jlang.RPAREN) {
+			p.Error(fmt.Sprintf("trailing comma in parameter list, line %d, col %d",
+				p.curToken.Line+1, p.curToken.Column+1))
+			return false
+		}
+	}
+
+	return p.expectPeek(jlang.RPAREN)
+}
+
+func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 
 	blockStmt := &ast.BlockStatement{
 		Token:      p.curToken,
 		Statements: make([]ast.Statement, 0),
 	}
 
+	p.openScope()
+	defer p.closeScope()
+
 	p.next()
 	for !p.curTokenIs(jlang.RBRACE) && !p.curTokenIs(jlang.EOF) {
 		stmt := p.parseStatement()
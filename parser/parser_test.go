@@ -66,6 +66,23 @@ func checkParserErrors(t *testing.T, p *Parser) {
 	t.FailNow()
 }
 
+// checkParserError checks that the first error of parsing input is expected.
+// Program is printed too since the REPL prints it despite errors.
+func checkParserError(t *testing.T, input string, expected string) {
+	l := jlang.New(input)
+	p := New(l)
+	_ = p.Parse().String()
+
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected error %q for %q. got none", expected, input)
+		return
+	}
+
+	if p.Errors()[0] != expected {
+		t.Errorf("error wrong for %q. expected=%q, got=%q", input, expected, p.Errors()[0])
+	}
+}
+
 func TestParser_Parse_ReturnStatements(t *testing.T) {
 	input := `
 	return 5;
@@ -148,7 +165,7 @@ func TestParser_Parse_LiteralExpression(t *testing.T) {
 	}
 
 	if literal.Value != 5 {
-		t.Errorf("IntegerLiteral.Value not %s, got=%s", "5", literal.Value)
+		t.Errorf("IntegerLiteral.Value not %s, got=%d", "5", literal.Value)
 	}
 }
 
@@ -534,7 +551,7 @@ func TestParser_Parse_FuctionExpression(t *testing.T) {
 
 	bodyStmt, ok := exp.Body.Statements[0].(*ast.ExpressionStatement)
 	if !ok {
-		t.Fatalf("Body Statements[0] is not *ast.ReturnStatement. got=%d", bodyStmt)
+		t.Fatalf("Body Statements[0] is not *ast.ReturnStatement. got=%T", exp.Body.Statements[0])
 	}
 
 	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
@@ -704,3 +721,1337 @@ func TestParser_asd(t *testing.T) {
 	parser.Parse()
 	checkParserErrors(t, parser)
 }
+
+func TestParser_Parse_FunctionStatement(t *testing.T) {
+
+	input := `
+	fn isEven(n) { if (n == 0) { true } else { isOdd(n - 1) } }
+	fn isOdd(n) { if (n == 0) { false } else { isEven(n - 1) } }
+	let add = fn(x, y) { x + y };
+	`
+	l := jlang.New(input)
+	parser := New(l)
+	program := parser.Parse()
+	checkParserErrors(t, parser)
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain 3 statements. got=%d",
+			len(program.Statements))
+	}
+
+	tests := []string{"isEven", "isOdd"}
+	for i, name := range tests {
+		stmt, ok := program.Statements[i].(*ast.FunctionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[%d] is not *ast.FunctionStatement. got=%T",
+				i, program.Statements[i])
+		}
+
+		if stmt.Name.Value != name {
+			t.Errorf("stmt.Name.Value not %s. got=%s", name, stmt.Name.Value)
+		}
+
+		if stmt.Function.Name != name {
+			t.Errorf("stmt.Function.Name not %s. got=%s", name, stmt.Function.Name)
+		}
+
+		if len(stmt.Function.Args) != 1 {
+			t.Fatalf("number of function arguments wrong. want 1. got=%d", len(stmt.Function.Args))
+		}
+
+		testLiteralExpression(t, stmt.Function.Args[0], "n")
+	}
+
+	letStmt := program.Statements[2].(*ast.LetStatement)
+	fn, ok := letStmt.Value.(*ast.FunctionExpression)
+	if !ok {
+		t.Fatalf("letStmt.Value is not *ast.FunctionExpression. got=%T", letStmt.Value)
+	}
+
+	if fn.Name != "add" {
+		t.Errorf("fn.Name not %s. got=%s", "add", fn.Name)
+	}
+
+	expected := "fn isEven(n){if((n == 0)){true\n}else{isOdd((n - 1))\n}}"
+	if program.Statements[0].String() != expected {
+		t.Errorf("expected=%q, got=%q", expected, program.Statements[0].String())
+	}
+}
+
+func TestParser_Parse_FunctionParameters(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedArgs  []string
+		expectedError string
+	}{
+		{"fn() {}", []string{}, ""},
+		{"fn(x) {}", []string{"x"}, ""},
+		{"fn(x, y, z) {}", []string{"x", "y", "z"}, ""},
+		{"fn(x, x) {}", nil, "duplicate parameter x, line 1, col 7"},
+		{"fn(x, 1) {}", nil, "expected parameter name, got INT instead, line 1, col 7"},
+		{"fn(x,) {}", nil, "trailing comma in parameter list, line 1, col 5"},
+		{"fn(x y) {}", nil, "expected next token to be ), got IDENT instead, line 1, col 6"},
+	}
+
+	for _, tt := range tests {
+		if tt.expectedError != "" {
+			checkParserError(t, tt.input, tt.expectedError)
+			continue
+		}
+
+		l := jlang.New(tt.input)
+		p := New(l)
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		fn, ok := stmt.Expression.(*ast.FunctionExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not *ast.FunctionExpression. got=%T", stmt.Expression)
+		}
+
+		if len(fn.Args) != len(tt.expectedArgs) {
+			t.Fatalf("number of function arguments wrong. want %d. got=%d",
+				len(tt.expectedArgs), len(fn.Args))
+		}
+
+		for i, arg := range tt.expectedArgs {
+			testLiteralExpression(t, fn.Args[i], arg)
+		}
+	}
+}
+
+func TestParser_Parse_DefaultAndRestParameters(t *testing.T) {
+	tests := []struct {
+		input         string
+		expected      string
+		expectedError string
+	}{
+		{"fn(x, y = 10) {}", "fn(x, y = 10){}", ""},
+		{"fn(x = 1 + 2, y = x) {}", "fn(x = (1 + 2), y = x){}", ""},
+		{"fn(first, ...rest) {}", "fn(first, ...rest){}", ""},
+		{"fn(x, y = 1, ...rest) {}", "fn(x, y = 1, ...rest){}", ""},
+		{"fn(...rest) {}", "fn(...rest){}", ""},
+		{"fn(x = 1, y) {}", "", "parameter y without default follows parameter with default, line 1, col 11"},
+		{"fn(...rest, x) {}", "", "rest parameter rest must be last, line 1, col 7"},
+		{"fn(x, ...x) {}", "", "duplicate parameter x, line 1, col 10"},
+		{"fn(x = ) {}", "", "no prefix parse function for ) found"},
+		{"fn(x = )) {}", "", "no prefix parse function for ) found"},
+	}
+
+	for _, tt := range tests {
+		if tt.expectedError != "" {
+			checkParserError(t, tt.input, tt.expectedError)
+			continue
+		}
+
+		l := jlang.New(tt.input)
+		p := New(l)
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestParser_Parse_SpreadAndKeywordArguments(t *testing.T) {
+	tests := []struct {
+		input         string
+		expected      string
+		expectedError string
+	}{
+		{"f(...list)", "f(...list)", ""},
+		{"f(1, ...a, ...b)", "f(1, ...a, ...b)", ""},
+		{"f(y: 2, x: 1)", "f(y: 2, x: 1)", ""},
+		{"f(1, ...a, y: 1 + 2)", "f(1, ...a, y: (1 + 2))", ""},
+		{"f(y: 2, 1)", "", "positional argument follows keyword argument, line 1, col 9"},
+		{"f(y: 2, y: 1)", "", "duplicate keyword argument y, line 1, col 9"},
+		{"f(...))", "", "no prefix parse function for ) found"},
+		{"f(k: ))", "", "no prefix parse function for ) found"},
+		{"f(1, ))", "", "no prefix parse function for ) found"},
+	}
+
+	for _, tt := range tests {
+		if tt.expectedError != "" {
+			checkParserError(t, tt.input, tt.expectedError)
+			continue
+		}
+
+		l := jlang.New(tt.input)
+		p := New(l)
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestParser_Parse_DestructuringLetStatement(t *testing.T) {
+	tests := []struct {
+		input         string
+		expected      string
+		expectedNames []string
+		expectedError string
+	}{
+		{"let [a, b] = list;", "let [a, b] = list;", []string{"a", "b"}, ""},
+		{"let [a, b, ...rest] = list;", "let [a, b, ...rest] = list;", []string{"a", "b", "rest"}, ""},
+		{"let {name, age: years} = person;", "let {name, age: years} = person;", []string{"name", "years"}, ""},
+		{"let {name, pos: [x, y]} = person;", "let {name, pos: [x, y]} = person;", []string{"name", "x", "y"}, ""},
+		{"let [{id}, [a, ...b]] = rows;", "let [{id}, [a, ...b]] = rows;", []string{"id", "a", "b"}, ""},
+		{"let [] = list;", "let [] = list;", []string{}, ""},
+		{"let [...rest, a] = list;", "", nil, "rest element rest must be last, line 1, col 9"},
+		{"let [a, a] = list;", "", nil, "duplicate binding a in pattern [a, a], line 1, col 10"},
+		{"let {1} = hash;", "", nil, "expected next token to be IDENT, got INT instead, line 1, col 6"},
+		{"let [a b] = list;", "", nil, "expected next token to be ,, got IDENT instead, line 1, col 8"},
+	}
+
+	for _, tt := range tests {
+		if tt.expectedError != "" {
+			checkParserError(t, tt.input, tt.expectedError)
+			continue
+		}
+
+		l := jlang.New(tt.input)
+		p := New(l)
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+
+		stmt, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.LetStatement. got=%T", program.Statements[0])
+		}
+
+		names := ast.PatternNames(stmt.Pattern)
+		if fmt.Sprint(names) != fmt.Sprint(tt.expectedNames) {
+			t.Errorf("pattern names wrong. expected=%v, got=%v", tt.expectedNames, names)
+		}
+	}
+}
+
+func TestParser_Parse_MatchExpression(t *testing.T) {
+	input := `match (x) {
+		0 => 1,
+		-1 => { let y = 2; y }
+		"a" => 3,
+		[a, b] if a > b => a,
+		{name, pos: [_, y]} => y
+		n => n * 2
+	}`
+
+	l := jlang.New(input)
+	parser := New(l)
+	program := parser.Parse()
+	checkParserErrors(t, parser)
+
+	if len(parser.Warnings()) != 0 {
+		t.Errorf("unexpected warnings. got=%v", parser.Warnings())
+	}
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+			len(program.Statements))
+	}
+
+	expStmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := expStmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not *ast.MatchExpression. got=%T", expStmt.Expression)
+	}
+
+	testIdentifier(t, exp.Subject, "x")
+
+	tests := []struct {
+		pattern string
+		guard   string
+		body    string
+	}{
+		{"0", "", "1"},
+		{"(-1)", "", "let y = 2;y"},
+		{`"a"`, "", "3"},
+		{"[a, b]", "(a > b)", "a"},
+		{"{name, pos: [_, y]}", "", "y"},
+		{"n", "", "(n * 2)"},
+	}
+
+	if len(exp.Arms) != len(tests) {
+		t.Fatalf("number of arms wrong. want %d. got=%d", len(tests), len(exp.Arms))
+	}
+
+	for i, tt := range tests {
+		arm := exp.Arms[i]
+		if arm.Pattern.String() != tt.pattern {
+			t.Errorf("arms[%d] pattern wrong. expected=%q, got=%q", i, tt.pattern, arm.Pattern.String())
+		}
+
+		guard := ""
+		if arm.Guard != nil {
+			guard = arm.Guard.String()
+		}
+		if guard != tt.guard {
+			t.Errorf("arms[%d] guard wrong. expected=%q, got=%q", i, tt.guard, guard)
+		}
+
+		if arm.Body.String() != tt.body {
+			t.Errorf("arms[%d] body wrong. expected=%q, got=%q", i, tt.body, arm.Body.String())
+		}
+	}
+}
+
+func TestParser_Parse_MatchExpressionWarnings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"match (x) { true => 1, false => 0 }", []string{}},
+		{"match (x) { _ => 1 }", []string{}},
+		{"match (x) { n if n > 0 => 1, n => 0 }", []string{}},
+		{"match (x) { 1 => 1, 2 => 2 }",
+			[]string{"non-exhaustive match expression, add a wildcard arm, line 1, col 1"}},
+		{"match (x) { n if n > 0 => 1 }",
+			[]string{"non-exhaustive match expression, add a wildcard arm, line 1, col 1"}},
+		{"match (x) { _ => 1, 1 => 2 }",
+			[]string{"unreachable match arm 1, line 1, col 21"}},
+		{"match (x) { 1 => 1, 1 if y => 2, _ => 3 }",
+			[]string{"unreachable match arm 1, line 1, col 21"}},
+		{`match (x) { "a" => 1, "b" => 2, "a" => 3, _ => 4 }`,
+			[]string{`unreachable match arm "a", line 1, col 34`}},
+		{"match (x) { true => 1, false => 0, _ => 2 }",
+			[]string{"unreachable match arm _, line 1, col 36"}},
+	}
+
+	for _, tt := range tests {
+		l := jlang.New(tt.input)
+		p := New(l)
+		p.Parse()
+		checkParserErrors(t, p)
+
+		if fmt.Sprint(p.Warnings()) != fmt.Sprint(tt.expected) {
+			t.Errorf("warnings wrong for %q. expected=%q, got=%q", tt.input, tt.expected, p.Warnings())
+		}
+	}
+}
+
+func TestParser_Parse_MatchExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"match (x) {}", "match expression has no arms, line 1, col 1"},
+		{"match (x) { 1 2 }", "expected next token to be =>, got INT instead, line 1, col 15"},
+		{"match (x) { [a, a] => a }", "duplicate binding a in pattern [a, a], line 1, col 18"},
+		{"let [0, a] = x;", "literal pattern 0 is not allowed in let statement, line 1, col 6"},
+		{`let ["a", b] = x;`, `literal pattern "a" is not allowed in let statement, line 1, col 7`},
+		{`match (x) { "a${y}" => 1 }`, "expected pattern, got STRING_PART instead, line 1, col 14"},
+		{`match (x) { "\q" => 1 }`, `could not parse "\\q" as string: unknown escape sequence \q, line 1, col 14`},
+	}
+
+	for _, tt := range tests {
+		checkParserError(t, tt.input, tt.expectedError)
+	}
+}
+
+func TestParser_Parse_StringLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"hello world"`, "hello world"},
+		{`""`, ""},
+		{`"a\"b\\c\n\$"`, "a\"b\\c\n$"},
+	}
+
+	for _, tt := range tests {
+		l := jlang.New(tt.input)
+		p := New(l)
+		program := p.Parse()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.StringLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+		}
+
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %q. got=%q", tt.expected, literal.Value)
+		}
+
+		if literal.String() != tt.input {
+			t.Errorf("literal.String() not %q. got=%q", tt.input, literal.String())
+		}
+	}
+}
+
+func TestParser_Parse_InterpolatedString(t *testing.T) {
+	tests := []struct {
+		input         string
+		expected      string
+		concatenation string
+	}{
+		{
+			`"hello ${name}, you have ${count + 1} items"`,
+			`"hello ${name}, you have ${(count + 1)} items"`,
+			`(((("hello " + name) + ", you have ") + (count + 1)) + " items")`,
+		},
+		{
+			`"${x}"`,
+			`"${x}"`,
+			`("" + x)`,
+		},
+		{
+			`"${f(fn(x) { x })}!"`,
+			`"${f(fn(x){x})}!"`,
+			`(("" + f(fn(x){x})) + "!")`,
+		},
+		{
+			`"outer ${"inner ${y}"}"`,
+			`"outer ${"inner ${y}"}"`,
+			`("outer " + "inner ${y}")`,
+		},
+	}
+
+	for _, tt := range tests {
+		l := jlang.New(tt.input)
+		p := New(l)
+		program := p.Parse()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+				len(program.Statements))
+		}
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.InterpolatedString)
+		if !ok {
+			t.Fatalf("exp not *ast.InterpolatedString. got=%T", stmt.Expression)
+		}
+
+		if exp.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, exp.String())
+		}
+
+		if exp.Concatenation().String() != tt.concatenation {
+			t.Errorf("concatenation expected=%q, got=%q", tt.concatenation, exp.Concatenation().String())
+		}
+	}
+}
+
+func TestParser_Parse_StringErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`"abc`, "unterminated string, line 1, col 1"},
+		{`"abc\`, "unterminated string, line 1, col 1"},
+		{`let x = "a ${"b"} c`, "unterminated string, line 1, col 9"},
+		{`"a${)}b"`, "no prefix parse function for ) found"},
+		{`"a\qb"`, `could not parse "a\\qb" as string: unknown escape sequence \q, line 1, col 2`},
+		{`"a ${x y}"`, "expected next token to be STRING, got IDENT instead, line 1, col 8"},
+	}
+
+	for _, tt := range tests {
+		checkParserError(t, tt.input, tt.expectedError)
+	}
+}
+
+func TestParser_Parse_PipeExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		call     string
+	}{
+		{
+			"data |> filter(isEven) |> map(double)",
+			"((data |> filter(isEven)) |> map(double))",
+			"map(filter(data, isEven), double)",
+		},
+		{
+			"x |> f",
+			"(x |> f)",
+			"f(x)",
+		},
+		{
+			"a + b |> f(c == d)",
+			"((a + b) |> f((c == d)))",
+			"f((a + b), (c == d))",
+		},
+		{
+			"1 == 2 |> not",
+			"((1 == 2) |> not)",
+			"not((1 == 2))",
+		},
+		{
+			"x |> fn(y) { y }",
+			"(x |> fn(y){y})",
+			"fn(y){y}(x)",
+		},
+	}
+
+	for _, tt := range tests {
+		l := jlang.New(tt.input)
+		p := New(l)
+		program := p.Parse()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.PipeExpression)
+		if !ok {
+			t.Fatalf("exp not *ast.PipeExpression. got=%T", stmt.Expression)
+		}
+
+		if exp.Call().String() != tt.call {
+			t.Errorf("call expected=%q, got=%q", tt.call, exp.Call().String())
+		}
+	}
+
+	for _, input := range []string{"x |> 5", "x |> 5 |> f"} {
+		l := jlang.New(input)
+		p := New(l)
+		program := p.Parse()
+
+		expectedError := "right side of |> must be a function or a call, got 5, line 1, col 3"
+		if len(p.Errors()) == 0 || p.Errors()[0] != expectedError {
+			t.Errorf("error wrong for %q. expected=%q, got=%q", input, expectedError, p.Errors())
+		}
+
+		// program with errors is still printed by the REPL
+		_ = program.String()
+	}
+}
+
+func TestParser_Parse_SelectorExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"obj.field", "obj.field"},
+		{"a.b.c", "a.b.c"},
+		{"obj.method(x, y)", "obj.method(x, y)"},
+		{`"abc".len()`, `"abc".len()`},
+		{"list.push(x).len()", "list.push(x).len()"},
+		{"-a.b * c.d", "((-a.b) * c.d)"},
+		{"a + f(x).y", "(a + f(x).y)"},
+		{"xs |> list.map(f)", "(xs |> list.map(f))"},
+	}
+
+	for _, tt := range tests {
+		l := jlang.New(tt.input)
+		p := New(l)
+		program := p.Parse()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	l := jlang.New("obj.method(x)")
+	p := New(l)
+	program := p.Parse()
+	checkParserErrors(t, p)
+
+	call, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.CallExpression. got=%T", program.Statements[0])
+	}
+
+	selector, ok := call.Function.(*ast.SelectorExpression)
+	if !ok {
+		t.Fatalf("call.Function not *ast.SelectorExpression. got=%T", call.Function)
+	}
+
+	testIdentifier(t, selector.Object, "obj")
+	testIdentifier(t, selector.Selector, "method")
+
+	checkParserError(t, "obj.1", "expected next token to be IDENT, got INT instead, line 1, col 5")
+}
+
+func TestParser_Parse_StructStatement(t *testing.T) {
+	input := `
+	struct Point { x, y }
+	let p = Point(1, 2);
+	let q = Point(y: 2, x: 1);
+	p.x + q.y
+	`
+
+	l := jlang.New(input)
+	parser := New(l)
+	program := parser.Parse()
+	checkParserErrors(t, parser)
+
+	if len(program.Statements) != 4 {
+		t.Fatalf("program.Statements does not contain 4 statements. got=%d",
+			len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.StructStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.StructStatement. got=%T", program.Statements[0])
+	}
+
+	if stmt.Name.Value != "Point" {
+		t.Errorf("stmt.Name.Value not %s. got=%s", "Point", stmt.Name.Value)
+	}
+
+	if len(stmt.Fields) != 2 {
+		t.Fatalf("number of fields wrong. want 2. got=%d", len(stmt.Fields))
+	}
+
+	testLiteralExpression(t, stmt.Fields[0], "x")
+	testLiteralExpression(t, stmt.Fields[1], "y")
+
+	if stmt.String() != "struct Point { x, y }" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestParser_Parse_StructErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"struct Point { x, x }", "duplicate field x of struct Point, line 1, col 19"},
+		{"struct Point { x, y } Point(1, 2, 3)", "Point() takes 2 arguments, got 3, line 1, col 28"},
+		{"struct Point { x, y } Point(1)", "Point() missing argument y, takes 2 arguments, line 1, col 28"},
+		{"struct Point { x, y } Point(x: 1, z: 2)", "Point() got an unexpected keyword argument z, line 1, col 28"},
+		{"struct Point { x, y } let p = Point(1, 2); p.z", "unknown field z of struct Point, line 1, col 46"},
+		{"struct Point { x, y } let p = Point(1, 2); let q = p; q.z", "unknown field z of struct Point, line 1, col 57"},
+		{"struct Point { x, y } Point(1, 2).z", "unknown field z of struct Point, line 1, col 35"},
+		{"struct Point { x, y } fn f() { let p = Point(1, 2); p.z }", "unknown field z of struct Point, line 1, col 55"},
+		{"struct Point { x, y } let p = Point(1, 2); fn f(q = p.z, p) { q }",
+			"unknown field z of struct Point, line 1, col 55"},
+		{"struct Point { x, y } let p = Point(1, 2); enum E { A(p) } p.z",
+			"unknown field z of struct Point, line 1, col 62"},
+	}
+
+	for _, tt := range tests {
+		checkParserError(t, tt.input, tt.expectedError)
+	}
+}
+
+func TestParser_Parse_StructShadowing(t *testing.T) {
+	tests := []string{
+		// parameter shadows variable of known struct type
+		"struct Point { x, y } let p = Point(1, 2); let f = fn(p) { p.z };",
+		// variable shadows struct
+		"struct Point { x, y } let g = fn() { let Point = fn(a, b, c) { a }; Point(1, 2, 3) };",
+		// match arm binding shadows variable
+		"struct Point { x, y } let p = Point(1, 2); match (x) { p => p.z }",
+		// block scope ends
+		"struct Point { x, y } if (true) { let p = Point(1, 2); } p.z",
+		// spread arguments are checked at runtime
+		"struct Point { x, y } Point(...xs)",
+		// comprehension binding shadows variable in element and condition
+		"struct Point { x, y } let p = Point(1, 2); [p.z for p in others if p.w]",
+		"struct Point { x, y } let p = Point(1, 2); {p.z: p.w for p in others}",
+		"struct Point { x, y } let p = Point(1, 2); [[p.z for p in ps] for ps in others]",
+		// default sees parameters before it
+		"struct Point { x, y } let p = Point(1, 2); fn f(p, q = p.z) { q }",
+	}
+
+	for _, input := range tests {
+		l := jlang.New(input)
+		p := New(l)
+		p.Parse()
+		checkParserErrors(t, p)
+	}
+}
+
+func TestParser_Parse_EnumStatement(t *testing.T) {
+	input := `
+	enum Shape { Circle(r), Rect(w, h), Empty }
+	let area = fn(s) {
+		match (s) {
+			Circle(r) => 3 * r * r,
+			Rect(w, h) => w * h,
+			Empty => 0
+		}
+	};
+	area(Rect(2, 3))
+	`
+
+	l := jlang.New(input)
+	parser := New(l)
+	program := parser.Parse()
+	checkParserErrors(t, parser)
+
+	if len(parser.Warnings()) != 0 {
+		t.Errorf("unexpected warnings. got=%v", parser.Warnings())
+	}
+
+	stmt, ok := program.Statements[0].(*ast.EnumStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not *ast.EnumStatement. got=%T", program.Statements[0])
+	}
+
+	if stmt.String() != "enum Shape { Circle(r), Rect(w, h), Empty }" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+
+	tests := []struct {
+		name   string
+		fields []string
+	}{
+		{"Circle", []string{"r"}},
+		{"Rect", []string{"w", "h"}},
+		{"Empty", []string{}},
+	}
+
+	if len(stmt.Variants) != len(tests) {
+		t.Fatalf("number of variants wrong. want %d. got=%d", len(tests), len(stmt.Variants))
+	}
+
+	for i, tt := range tests {
+		variant := stmt.Variants[i]
+		testIdentifier(t, variant.Name, tt.name)
+
+		if len(variant.Fields) != len(tt.fields) {
+			t.Fatalf("number of fields of %s wrong. want %d. got=%d", tt.name, len(tt.fields), len(variant.Fields))
+		}
+
+		for j, field := range tt.fields {
+			testIdentifier(t, variant.Fields[j], field)
+		}
+	}
+
+	let := program.Statements[1].(*ast.LetStatement)
+	fn := let.Value.(*ast.FunctionExpression)
+	match := fn.Body.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.MatchExpression)
+
+	patterns := []string{"Circle(r)", "Rect(w, h)", "Empty"}
+	for i, expected := range patterns {
+		pattern, ok := match.Arms[i].Pattern.(*ast.VariantPattern)
+		if !ok {
+			t.Fatalf("arms[%d] pattern is not *ast.VariantPattern. got=%T", i, match.Arms[i].Pattern)
+		}
+
+		if pattern.String() != expected {
+			t.Errorf("arms[%d] pattern wrong. expected=%q, got=%q", i, expected, pattern.String())
+		}
+	}
+}
+
+func TestParser_Parse_EnumMatchWarnings(t *testing.T) {
+	enum := "enum Shape { Circle(r), Rect(w, h), Empty } "
+
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"match (s) { Circle(_) => 1, Rect(_, _) => 2, Empty => 3 }", []string{}},
+		{"match (s) { Circle(r) => 1, _ => 2 }", []string{}},
+		{"match (s) { Circle(r) => 1 }",
+			[]string{"non-exhaustive match expression, missing variants Rect, Empty of enum Shape, line 1, col 45"}},
+		{"match (s) { Circle(r) if r > 1 => 1, Rect(w, h) => 2, Empty => 3 }",
+			[]string{"non-exhaustive match expression, missing variants Circle of enum Shape, line 1, col 45"}},
+		{"match (s) { Circle(0) => 1, Rect(w, h) => 2, Empty => 3 }",
+			[]string{"non-exhaustive match expression, missing variants Circle of enum Shape, line 1, col 45"}},
+		{"match (s) { Circle(r) => 1, Rect(w, h) => 2, Empty => 3, _ => 4 }",
+			[]string{"unreachable match arm _, line 1, col 102"}},
+		{"match (s) { Circle(r) => 1, Circle(0) => 2, _ => 3 }",
+			[]string{"unreachable match arm Circle(0), line 1, col 73"}},
+	}
+
+	for _, tt := range tests {
+		l := jlang.New(enum + tt.input)
+		p := New(l)
+		p.Parse()
+		checkParserErrors(t, p)
+
+		if fmt.Sprint(p.Warnings()) != fmt.Sprint(tt.expected) {
+			t.Errorf("warnings wrong for %q. expected=%q, got=%q", tt.input, tt.expected, p.Warnings())
+		}
+	}
+}
+
+func TestParser_Parse_EnumErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"enum Shape { Circle(r), Circle(d) }", "duplicate variant Circle of enum Shape, line 1, col 25"},
+		{"enum Shape { Circle(r = 1) }", "fields of variant Circle must be plain identifiers, line 1, col 14"},
+		{"enum Shape { Circle(r) } Circle(1, 2)", "Circle() takes 1 argument, got 2, line 1, col 32"},
+		{"enum Shape { Circle(r) } match (s) { Circle(a, b) => 1 }",
+			"variant pattern Circle(a, b) has 2 fields, want 1, line 1, col 38"},
+		{"match (s) { Square(a) => 1 }", "unknown variant Square, line 1, col 13"},
+	}
+
+	for _, tt := range tests {
+		checkParserError(t, tt.input, tt.expectedError)
+	}
+}
+
+func TestParser_Parse_TryStatement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		catch    string
+		finally  bool
+	}{
+		{"try { f() } catch (e) { g(e) }", "try{f()}catch(e){g(e)}", "e", false},
+		{"try { f() } finally { close() }", "try{f()}finally{close()}", "", true},
+		{"try { f() } catch (err) { 1 } finally { 2 }", "try{f()}catch(err){1}finally{2}", "err", true},
+	}
+
+	for _, tt := range tests {
+		l := jlang.New(tt.input)
+		p := New(l)
+		program := p.Parse()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+
+		stmt, ok := program.Statements[0].(*ast.TryStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not *ast.TryStatement. got=%T", program.Statements[0])
+		}
+
+		if tt.catch != "" {
+			testIdentifier(t, stmt.CatchParam, tt.catch)
+		} else if stmt.Catch != nil {
+			t.Errorf("stmt.Catch was not nil. got=%q", stmt.Catch)
+		}
+
+		if (stmt.Finally != nil) != tt.finally {
+			t.Errorf("stmt.Finally wrong. got=%v", stmt.Finally)
+		}
+	}
+}
+
+func TestParser_Parse_ThrowStatement(t *testing.T) {
+	l := jlang.New(`throw "bad " + x; throw error(1)`)
+	p := New(l)
+	program := p.Parse()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d",
+			len(program.Statements))
+	}
+
+	expected := []string{`throw ("bad " + x);`, `throw error(1);`}
+	for i, stmt := range program.Statements {
+		throwStmt, ok := stmt.(*ast.ThrowStatement)
+		if !ok {
+			t.Fatalf("program.Statements[%d] is not *ast.ThrowStatement. got=%T", i, stmt)
+		}
+
+		if throwStmt.String() != expected[i] {
+			t.Errorf("expected=%q, got=%q", expected[i], throwStmt.String())
+		}
+	}
+}
+
+func TestParser_Parse_TryErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"try { f() }", "try statement needs catch or finally block, line 1, col 1"},
+		{"try { f() } catch { g() }", "expected next token to be (, got { instead, line 1, col 19"},
+		{"try { f() } catch (1) { g() }", "expected next token to be IDENT, got INT instead, line 1, col 20"},
+		{"throw;", "no prefix parse function for ; found"},
+	}
+
+	for _, tt := range tests {
+		checkParserError(t, tt.input, tt.expectedError)
+	}
+}
+
+func TestParser_Parse_PostfixExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn() { x? }", "fn(){(x?)}"},
+		{"fn() { f(x)? }", "fn(){(f(x)?)}"},
+		{"fn() { a + b? * c }", "fn(){(a + ((b?) * c))}"},
+		{"fn() { -x? }", "fn(){(-(x?))}"},
+		{"fn() { (obj.read()?).len() }", "fn(){(obj.read()?).len()}"},
+		{"fn() { (f()?)? }", "fn(){((f()?)?)}"},
+		{"fn() { let v = parse(s)?; v }", "fn(){let v = (parse(s)?);v}"},
+	}
+
+	for _, tt := range tests {
+		l := jlang.New(tt.input)
+		p := New(l)
+		program := p.Parse()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	checkParserError(t, "f(x)?", "? operator used outside of function, line 1, col 5")
+}
+
+func TestParser_Parse_ErrorBuiltins(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`error()`, "error() missing argument message, takes 1 argument, line 1, col 6"},
+		{`isError(1, 2)`, "isError() takes 1 argument, got 2, line 1, col 8"},
+		{`unwrap(x: 1)`, "unwrap() got an unexpected keyword argument x, line 1, col 7"},
+		{`fn() { error("a", "b") }`, "error() takes 1 argument, got 2, line 1, col 13"},
+	}
+
+	for _, tt := range tests {
+		checkParserError(t, tt.input, tt.expectedError)
+	}
+
+	for _, input := range []string{
+		`fn(s) { if (isError(s)) { return error("empty"); } unwrap(s) }`,
+		`error(message: "a")`,
+		// declarations shadow builtins
+		`let error = fn(a, b) { a }; error(1, 2)`,
+		`fn(unwrap) { unwrap() }`,
+	} {
+		l := jlang.New(input)
+		p := New(l)
+		p.Parse()
+		checkParserErrors(t, p)
+	}
+}
+
+func TestParser_Parse_Generator(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string
+		generator bool
+	}{
+		{"let g = fn*() { 1 };", "let g = fn*(){1};", true},
+		{"let g = fn(xs) { yield x; };", "let g = fn*(xs){yield x};", true},
+		{"let g = fn() { let v = yield; v };", "let g = fn*(){let v = yield;v};", true},
+		{"let g = fn() { f(yield 1, yield) };", "let g = fn*(){f(yield 1, yield)};", true},
+		{"let g = fn() { fn() { yield 1 } };", "let g = fn(){fn*(){yield 1}};", false},
+		{"fn* lines(s) { yield s }", "fn* lines(s){yield s}", true},
+		{"fn count(n) { yield n }", "fn* count(n){yield n}", true},
+		{"fn*() { yield 1 }()", "fn*(){yield 1}()", true},
+	}
+
+	for _, tt := range tests {
+		l := jlang.New(tt.input)
+		p := New(l)
+		program := p.Parse()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+
+		var fn *ast.FunctionExpression
+		switch stmt := program.Statements[0].(type) {
+		case *ast.LetStatement:
+			fn = stmt.Value.(*ast.FunctionExpression)
+		case *ast.FunctionStatement:
+			fn = stmt.Function
+		case *ast.ExpressionStatement:
+			fn = stmt.Expression.(*ast.CallExpression).Function.(*ast.FunctionExpression)
+		}
+
+		if fn.Generator != tt.generator {
+			t.Errorf("fn.Generator wrong for %q. expected=%t, got=%t", tt.input, tt.generator, fn.Generator)
+		}
+	}
+
+	checkParserError(t, "yield 1", "yield used outside of function, line 1, col 1")
+}
+
+func TestParser_Parse_RangeExpression(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string
+		inclusive bool
+	}{
+		{"0..10", "(0..10)", false},
+		{"1..=n", "(1..=n)", true},
+		{"a + 1..b * 2", "((a + 1)..(b * 2))", false},
+		{"0..n == r", "((0..n) == r)", false},
+		{"x < 0..5", "(x < (0..5))", false},
+		{"0..len(xs) - 1", "(0..(len(xs) - 1))", false},
+	}
+
+	for _, tt := range tests {
+		l := jlang.New(tt.input)
+		p := New(l)
+		program := p.Parse()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	l := jlang.New("1..=3")
+	p := New(l)
+	program := p.Parse()
+	checkParserErrors(t, p)
+
+	exp, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.RangeExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.RangeExpression. got=%T", program.Statements[0])
+	}
+
+	testIntegerLiteral(t, exp.Start, 1)
+	testIntegerLiteral(t, exp.End, 3)
+	if !exp.Inclusive {
+		t.Errorf("exp.Inclusive not true")
+	}
+
+	for _, input := range []string{"1..2..3", "1..2..3 + 4"} {
+		l = jlang.New(input)
+		p = New(l)
+		program = p.Parse()
+
+		expectedError := "range of range is not allowed, use parentheses, line 1, col 5"
+		if len(p.Errors()) == 0 || p.Errors()[0] != expectedError {
+			t.Errorf("error wrong for %q. expected=%q, got=%q", input, expectedError, p.Errors())
+		}
+
+		// program with errors is still printed by the REPL
+		_ = program.String()
+	}
+
+	if s := (&ast.RangeExpression{Token: jlang.Token{Val: ".."}}).String(); s != "(..)" {
+		t.Errorf("range without bounds wrong. expected=%q, got=%q", "(..)", s)
+	}
+}
+
+func TestParser_Parse_IndexAndSliceExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"list[0]", "list[0]"},
+		{"list[1:3]", "list[1:3]"},
+		{"str[:5]", "str[:5]"},
+		{"str[-2:]", "str[(-2):]"},
+		{"str[:]", "str[:]"},
+		{"a * b[i + 1]", "(a * b[(i + 1)])"},
+		{"m[i][j]", "m[i][j]"},
+		{"f(x)[0].name", "f(x)[0].name"},
+		{"xs[1:n - 1]", "xs[1:(n - 1)]"},
+	}
+
+	for _, tt := range tests {
+		l := jlang.New(tt.input)
+		p := New(l)
+		program := p.Parse()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	l := jlang.New("list[1:]")
+	p := New(l)
+	program := p.Parse()
+	checkParserErrors(t, p)
+
+	slice, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.SliceExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.SliceExpression. got=%T", program.Statements[0])
+	}
+
+	testIdentifier(t, slice.Left, "list")
+	testIntegerLiteral(t, slice.Low, 1)
+	if slice.High != nil {
+		t.Errorf("slice.High was not nil. got=%s", slice.High)
+	}
+}
+
+func TestParser_Parse_IndexExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"a[)]", "no prefix parse function for ) found"},
+		{"a?.[)]", "no prefix parse function for ) found"},
+		{"a[):2]", "no prefix parse function for ) found"},
+		{"a[1:)]", "no prefix parse function for ) found"},
+		{"a[1", "expected next token to be ], got EOF instead, line 1, col 4"},
+	}
+
+	for _, tt := range tests {
+		checkParserError(t, tt.input, tt.expectedError)
+	}
+}
+
+func TestParser_Parse_ArrayAndHashLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[]", "[]"},
+		{"[1, 2 * 2, f(x)]", "[1, (2 * 2), f(x)]"},
+		{"[1, 2,]", "[1, 2]"},
+		{"[[1], [2, 3]][0]", "[[1], [2, 3]][0]"},
+		{"{}", "{}"},
+		{`{"a": 1, k: v + 1}`, `{"a": 1, k: (v + 1)}`},
+		{`{"a": [1, 2]}["a"]`, `{"a": [1, 2]}["a"]`},
+	}
+
+	for _, tt := range tests {
+		l := jlang.New(tt.input)
+		p := New(l)
+		program := p.Parse()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestParser_Parse_Comprehension(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[x * 2 for x in xs if x > 0]", "[(x * 2) for x in xs if (x > 0)]"},
+		{"[x for x in 0..10]", "[x for x in (0..10)]"},
+		{"[a + b for [a, b] in pairs]", "[(a + b) for [a, b] in pairs]"},
+		{"[[x, y] for {x, y} in points]", "[[x, y] for {x, y} in points]"},
+		{"{k: v for [k, v] in pairs}", "{k: v for [k, v] in pairs}"},
+		{"{x: x * x for x in xs if x != 0}", "{x: (x * x) for x in xs if (x != 0)}"},
+		{"[[y for y in x] for x in xs]", "[[y for y in x] for x in xs]"},
+	}
+
+	for _, tt := range tests {
+		l := jlang.New(tt.input)
+		p := New(l)
+		program := p.Parse()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	l := jlang.New("[x * 2 for x in xs if x > 0]")
+	p := New(l)
+	program := p.Parse()
+	checkParserErrors(t, p)
+
+	exp, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.ListComprehension)
+	if !ok {
+		t.Fatalf("exp not *ast.ListComprehension. got=%T", program.Statements[0])
+	}
+
+	testInfixExpression(t, exp.Element, "x", "*", 2)
+	testIdentifier(t, exp.Clause.Pattern.(*ast.Identifier), "x")
+	testIdentifier(t, exp.Clause.Iterable, "xs")
+	testInfixExpression(t, exp.Clause.Condition, "x", ">", 0)
+}
+
+func TestParser_Parse_ComprehensionErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"[x for x xs]", "expected next token to be IN, got IDENT instead, line 1, col 10"},
+		{"[x for 1 in xs]", "literal pattern 1 is not allowed in comprehension, line 1, col 8"},
+		{"[a for [a, a] in xs]", "duplicate binding a in pattern [a, a], line 1, col 13"},
+		{"{k: v for x in xs, 1: 2}", "expected next token to be }, got , instead, line 1, col 18"},
+		{"{k for k in xs}", "expected next token to be :, got FOR instead, line 1, col 4"},
+		{"struct Point { x, y } let p = Point(1, 2); [p.z for q in xs]",
+			"unknown field z of struct Point, line 1, col 47"},
+		{"struct Point { x, y } let p = Point(1, 2); [p.z, 1]",
+			"unknown field z of struct Point, line 1, col 47"},
+		{"struct Point { x, y } let p = Point(1, 2); {p.z: 1 for q in xs}",
+			"unknown field z of struct Point, line 1, col 47"},
+		{"struct Point { x, y } let p = Point(1, 2); [x for x in p.z]",
+			"unknown field z of struct Point, line 1, col 58"},
+		{"[1, )]", "no prefix parse function for ) found"},
+		{"[)]", "no prefix parse function for ) found"},
+		{"{a: )}", "no prefix parse function for ) found"},
+		{"{): 1}", "no prefix parse function for ) found"},
+		{"{a: 1, b: )}", "no prefix parse function for ) found"},
+		{"[) for x in y]", "no prefix parse function for ) found"},
+		{"[x for x in )]", "no prefix parse function for ) found"},
+		{"[x for x in y if )]", "no prefix parse function for ) found"},
+	}
+
+	for _, tt := range tests {
+		checkParserError(t, tt.input, tt.expectedError)
+	}
+}
+
+func TestParser_Parse_NullSafeExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"null", "null"},
+		{"a?.b", "a?.b"},
+		{"a?.b.c?.d", "a?.b.c?.d"},
+		{"a?.[i]", "a?.[i]"},
+		{"a?.[1:2]", "a?.[1:2]"},
+		{"f?.(x)", "f?.(x)"},
+		{"config?.db?.port ?? 5432", "(config?.db?.port ?? 5432)"},
+		{"a ?? b ?? c", "((a ?? b) ?? c)"},
+		{"a ?? b == c", "(a ?? (b == c))"},
+		{"a ?? b |> f", "((a ?? b) |> f)"},
+		{"a + b ?? c * d", "((a + b) ?? (c * d))"},
+		{"(a?.b).c", "(a?.b).c"},
+		{"(a?.b)?.c", "(a?.b)?.c"},
+		{"(a?.[0])(x)", "(a?.[0])(x)"},
+		{"(a.b).c", "a.b.c"},
+		{"((a?.b))", "(a?.b)"},
+		{"(a?.b + 1).c", "(a?.b + 1).c"},
+		{"x |> (a?.f)", "(x |> (a?.f))"},
+	}
+
+	for _, tt := range tests {
+		l := jlang.New(tt.input)
+		p := New(l)
+		program := p.Parse()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	l := jlang.New("a?.b?.(x)?.[0]")
+	p := New(l)
+	program := p.Parse()
+	checkParserErrors(t, p)
+
+	index, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.IndexExpression)
+	if !ok || !index.Optional {
+		t.Fatalf("exp not optional *ast.IndexExpression. got=%T", program.Statements[0])
+	}
+
+	call, ok := index.Left.(*ast.CallExpression)
+	if !ok || !call.Optional {
+		t.Fatalf("index.Left not optional *ast.CallExpression. got=%T", index.Left)
+	}
+
+	selector, ok := call.Function.(*ast.SelectorExpression)
+	if !ok || !selector.Optional {
+		t.Fatalf("call.Function not optional *ast.SelectorExpression. got=%T", call.Function)
+	}
+
+	// parentheses end short-circuit of the chain
+	l = jlang.New("(a?.b).c")
+	p = New(l)
+	program = p.Parse()
+	checkParserErrors(t, p)
+
+	selector, ok = program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.SelectorExpression)
+	if !ok || selector.Optional {
+		t.Fatalf("exp not *ast.SelectorExpression. got=%T", program.Statements[0])
+	}
+
+	chain, ok := selector.Object.(*ast.ChainExpression)
+	if !ok {
+		t.Fatalf("selector.Object not *ast.ChainExpression. got=%T", selector.Object)
+	}
+
+	if inner, ok := chain.Chain.(*ast.SelectorExpression); !ok || !inner.Optional {
+		t.Fatalf("chain.Chain not optional *ast.SelectorExpression. got=%T", chain.Chain)
+	}
+
+	l = jlang.New("match (x) { null => 0, _ => 1 }")
+	p = New(l)
+	program = p.Parse()
+	checkParserErrors(t, p)
+
+	expected := "match(x){null => {0}, _ => {1}}"
+	if program.String() != expected {
+		t.Errorf("expected=%q, got=%q", expected, program.String())
+	}
+}
+
+func TestParser_Parse_ImportExport(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "lib/strings" as s;`, `import "lib/strings" as s;`},
+		{`import "lib/strings"`, `import "lib/strings";`},
+		{`export let x = 1;`, `export let x = 1;`},
+		{`export fn add(a, b) { a + b }`, `export fn add(a, b){(a + b)}`},
+		{`export struct Point { x, y }`, `export struct Point { x, y }`},
+	}
+
+	for _, tt := range tests {
+		l := jlang.New(tt.input)
+		p := New(l)
+		program := p.Parse()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	l := jlang.New(`import "lib/strings"; import "lib/math" as m; export enum Color { Red, Green }`)
+	p := New(l)
+	program := p.Parse()
+	checkParserErrors(t, p)
+
+	names := []string{"strings", "m"}
+	for i, name := range names {
+		stmt, ok := program.Statements[i].(*ast.ImportStatement)
+		if !ok {
+			t.Fatalf("program.Statements[%d] is not *ast.ImportStatement. got=%T", i, program.Statements[i])
+		}
+
+		if stmt.Name() != name {
+			t.Errorf("import name wrong. expected=%q, got=%q", name, stmt.Name())
+		}
+	}
+
+	export, ok := program.Statements[2].(*ast.ExportStatement)
+	if !ok {
+		t.Fatalf("program.Statements[2] is not *ast.ExportStatement. got=%T", program.Statements[2])
+	}
+
+	if fmt.Sprint(export.Names()) != "[Color Red Green]" {
+		t.Errorf("exported names wrong. got=%v", export.Names())
+	}
+}
+
+func TestParser_Parse_ImportExportErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`fn() { import "a" }`, "import must be at top level, line 1, col 8"},
+		{`if (x) { export let y = 1; }`, "export must be at top level, line 1, col 10"},
+		{`import ""`, "import path is empty, line 1, col 9"},
+		{`import a`, "expected next token to be STRING, got IDENT instead, line 1, col 8"},
+		{`import "a" as 1`, "expected next token to be IDENT, got INT instead, line 1, col 15"},
+		{`export 1`, "expected declaration after export, got INT instead, line 1, col 8"},
+		{`export fn*() { yield 1 }`, "exported function needs a name, line 1, col 1"},
+	}
+
+	for _, tt := range tests {
+		checkParserError(t, tt.input, tt.expectedError)
+	}
+}
@@ -0,0 +1,248 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/junbeomlee/jlang/ast"
+)
+
+// binding is what the parser statically knows about a declared name.
+type binding struct {
+
+	// Struct declared with this name, nil if name is not a struct
+	structDecl *ast.StructStatement
+
+	// Struct type of the value bound to this name, nil if unknown
+	structType *ast.StructStatement
+
+	// Enum variant declared with this name and its enum, nil if name is not a variant
+	variant  *ast.EnumVariant
+	enumDecl *ast.EnumStatement
+
+	// Signature of builtin function with this name, nil if name is not a builtin
+	builtin *ast.FunctionExpression
+}
+
+// builtins are signatures of functions every program can call. They are declared
+// in the outermost scope, so their calls are checked and declarations shadow them.
+//
+//	error(message)  error value with message, which ? returns early with
+//	isError(value)  whether value is an error value
+//	unwrap(value)   value unless it is an error value, which fails with its message
+var builtins = []*ast.FunctionExpression{
+	builtin("error", "message"),
+	builtin("isError", "value"),
+	builtin("unwrap", "value"),
+}
+
+func builtin(name string, params ...string) *ast.FunctionExpression {
+	fn := &ast.FunctionExpression{
+		Name:     name,
+		Args:     []*ast.Identifier{},
+		Defaults: map[string]ast.Expression{},
+		Body:     &ast.BlockStatement{Statements: []ast.Statement{}},
+	}
+
+	for _, param := range params {
+		fn.Args = append(fn.Args, &ast.Identifier{Value: param})
+	}
+
+	return fn
+}
+
+func (p *Parser) declareBuiltins() {
+	for _, fn := range builtins {
+		p.declare(fn.Name, &binding{builtin: fn})
+	}
+}
+
+// scope records names declared in a block so that struct and enum usage
+// can be checked while parsing.
+type scope map[string]*binding
+
+func (p *Parser) openScope() {
+	p.scopes = append(p.scopes, scope{})
+}
+
+func (p *Parser) closeScope() {
+	p.scopes = p.scopes[:len(p.scopes)-1]
+}
+
+func (p *Parser) declare(name string, b *binding) {
+	p.scopes[len(p.scopes)-1][name] = b
+}
+
+// declarePattern declares names bound by pattern with unknown types.
+func (p *Parser) declarePattern(pattern ast.Pattern) {
+	for _, name := range ast.PatternNames(pattern) {
+		p.declare(name, &binding{})
+	}
+}
+
+func (p *Parser) lookup(name string) *binding {
+	for i := len(p.scopes) - 1; i >= 0; i-- {
+		if b, ok := p.scopes[i][name]; ok {
+			return b
+		}
+	}
+
+	return nil
+}
+
+// lookupStruct returns struct declaration named by expression, nil if expression
+// is not a known struct.
+func (p *Parser) lookupStruct(exp ast.Expression) *ast.StructStatement {
+	ident, ok := exp.(*ast.Identifier)
+	if !ok {
+		return nil
+	}
+
+	if b := p.lookup(ident.Value); b != nil {
+		return b.structDecl
+	}
+
+	return nil
+}
+
+// lookupVariant returns enum variant with given name and its enum, nil if
+// name is not a known variant.
+func (p *Parser) lookupVariant(name string) (*ast.EnumVariant, *ast.EnumStatement) {
+	if b := p.lookup(name); b != nil {
+		return b.variant, b.enumDecl
+	}
+
+	return nil, nil
+}
+
+// lookupConstructor returns signature of struct or variant constructor or builtin
+// named by expression, nil if expression is not a known constructor or builtin.
+func (p *Parser) lookupConstructor(exp ast.Expression) *ast.FunctionExpression {
+	if structDecl := p.lookupStruct(exp); structDecl != nil {
+		return structDecl.Constructor()
+	}
+
+	if ident, ok := exp.(*ast.Identifier); ok {
+		if variant, _ := p.lookupVariant(ident.Value); variant != nil {
+			return variant.Constructor()
+		}
+
+		if b := p.lookup(ident.Value); b != nil && b.builtin != nil {
+			return b.builtin
+		}
+	}
+
+	return nil
+}
+
+// structTypeOf returns struct type of the value of expression, nil if unknown.
+func (p *Parser) structTypeOf(exp ast.Expression) *ast.StructStatement {
+	switch e := exp.(type) {
+	case *ast.Identifier:
+		if b := p.lookup(e.Value); b != nil {
+			return b.structType
+		}
+	case *ast.CallExpression:
+		return p.lookupStruct(e.Function)
+	}
+
+	return nil
+}
+
+// checkConstructorCall reports calls of constructors of known struct or enum variant
+// and of builtins with wrong number of arguments or unknown fields.
+func (p *Parser) checkConstructorCall(call *ast.CallExpression) {
+	constructor := p.lookupConstructor(call.Function)
+	if constructor == nil {
+		return
+	}
+
+	positional := 0
+	keywords := []string{}
+
+	for _, arg := range call.Args {
+		switch a := arg.(type) {
+		case *ast.SpreadExpression:
+			// number of arguments is not known until runtime
+			return
+		case *ast.KeywordArgument:
+			keywords = append(keywords, a.Name.Value)
+		default:
+			positional++
+		}
+	}
+
+	if err := constructor.CheckArgs(positional, keywords); err != nil {
+		p.Error(fmt.Sprintf("%s, line %d, col %d",
+			err, call.Token.Line+1, call.Token.Column+1))
+	}
+}
+
+// deferredChecks holds field accesses of an expression which may turn out to be
+// the element of a comprehension. Names bound by its pattern are only known after
+// the element is parsed, so accesses to objects declared outside the element wait
+// until it is known whether the pattern shadows them.
+type deferredChecks struct {
+
+	// number of scopes open when the expression started
+	depth int
+
+	selectors []*ast.SelectorExpression
+}
+
+func (p *Parser) deferFieldChecks() {
+	p.deferred = append(p.deferred, &deferredChecks{depth: len(p.scopes)})
+}
+
+// resumeFieldChecks stops deferring started by the latest deferFieldChecks and
+// returns field accesses deferred since.
+func (p *Parser) resumeFieldChecks() []*ast.SelectorExpression {
+	checks := p.deferred[len(p.deferred)-1]
+	p.deferred = p.deferred[:len(p.deferred)-1]
+
+	return checks.selectors
+}
+
+func (p *Parser) checkFieldAccesses(selectors []*ast.SelectorExpression) {
+	for _, selector := range selectors {
+		p.checkFieldAccess(selector)
+	}
+}
+
+// declaredSince returns whether the name struct type of expression depends on is
+// declared in a scope opened after depth scopes.
+func (p *Parser) declaredSince(exp ast.Expression, depth int) bool {
+	name := ""
+	switch e := exp.(type) {
+	case *ast.Identifier:
+		name = e.Value
+	case *ast.CallExpression:
+		if ident, ok := e.Function.(*ast.Identifier); ok {
+			name = ident.Value
+		}
+	}
+
+	for i := len(p.scopes) - 1; i >= depth; i-- {
+		if _, ok := p.scopes[i][name]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkFieldAccess reports access to a field which struct type of object does not have.
+func (p *Parser) checkFieldAccess(selector *ast.SelectorExpression) {
+	if n := len(p.deferred); n > 0 && !p.declaredSince(selector.Object, p.deferred[n-1].depth) {
+		p.deferred[n-1].selectors = append(p.deferred[n-1].selectors, selector)
+		return
+	}
+
+	structType := p.structTypeOf(selector.Object)
+	if structType == nil || structType.HasField(selector.Selector.Value) {
+		return
+	}
+
+	p.Error(fmt.Sprintf("unknown field %s of struct %s, line %d, col %d",
+		selector.Selector.Value, structType.Name.Value,
+		selector.Selector.Token.Line+1, selector.Selector.Token.Column+1))
+}
@@ -0,0 +1,394 @@
+package jlang
+
+import (
+	"fmt"
+	"math"
+)
+
+// Peephole optimizes instructions of top level code and every function of bytecode
+// in place. It removes jumps to the next instruction, threads jumps to jumps, folds
+// arithmetic on two constants, drops values pushed only to be popped and fuses
+// OpGetLocal OpConstant OpAdd into OpAddLocalConstant. Jump targets, handlers and line
+// tables are moved along with the instructions, and instructions are never combined
+// across handler boundaries. Bytecode must be verified.
+func Peephole(b *Bytecode) error {
+	ins, handlers, lines, err := peephole(b, b.Instructions, b.Handlers, b.Lines)
+	if err != nil {
+		return err
+	}
+	b.Instructions, b.Handlers, b.Lines = ins, handlers, lines
+
+	for _, fn := range b.Functions {
+		ins, handlers, lines, err := peephole(b, fn.Instructions, fn.Handlers, fn.Lines)
+		if err != nil {
+			return err
+		}
+		fn.Instructions, fn.Handlers, fn.Lines = ins, handlers, lines
+	}
+
+	return nil
+}
+
+// peepholeNode is an instruction being optimized. Jump targets refer to nodes
+// rather than offsets so that instructions can be removed freely.
+type peepholeNode struct {
+	op       Opcode
+	operands []int
+	target   *peepholeNode
+	removed  bool
+
+	// source lines of line table starting at this instruction
+	lines []int
+
+	offset int
+}
+
+// peepholeHandler is a handler whose offsets refer to nodes.
+type peepholeHandler struct {
+	start, end, target *peepholeNode
+}
+
+type peepholeOptimizer struct {
+	bytecode *Bytecode
+	nodes    []*peepholeNode
+	handlers []*peepholeHandler
+
+	// end stands for the offset right after the last instruction
+	end *peepholeNode
+}
+
+func peephole(b *Bytecode, ins Instructions, handlers []Handler, lines []Line) (Instructions, []Handler, []Line, error) {
+	o := &peepholeOptimizer{bytecode: b, end: &peepholeNode{}}
+
+	byOffset := make(map[int]*peepholeNode)
+	for offset := 0; offset < len(ins); {
+		decoded, err := ReadInstruction(ins, offset)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		node := &peepholeNode{op: decoded.Opcode, operands: decoded.Operands}
+		o.nodes = append(o.nodes, node)
+		byOffset[offset] = node
+
+		offset = decoded.Next
+	}
+	byOffset[len(ins)] = o.end
+
+	for _, node := range o.nodes {
+		if isJump(node.op) {
+			node.target = byOffset[node.operands[0]]
+		}
+	}
+
+	for _, h := range handlers {
+		o.handlers = append(o.handlers, &peepholeHandler{
+			start:  byOffset[h.Start],
+			end:    byOffset[h.End],
+			target: byOffset[h.Target],
+		})
+	}
+
+	for _, l := range lines {
+		if node, ok := byOffset[l.Offset]; ok {
+			node.lines = append(node.lines, l.Line)
+		}
+	}
+
+	for o.optimize() {
+	}
+
+	return o.encode()
+}
+
+func isJump(op Opcode) bool {
+	return op == OpJump || op == OpJumpNotTruthy
+}
+
+// isPurePush returns whether op pushes a value without any other effect.
+func isPurePush(op Opcode) bool {
+	switch op {
+	case OpConstant, OpTrue, OpFalse, OpNull, OpGetLocal, OpGetGlobal:
+		return true
+	}
+
+	return false
+}
+
+// live returns instructions which are not removed.
+func (o *peepholeOptimizer) live() []*peepholeNode {
+	live := []*peepholeNode{}
+	for _, node := range o.nodes {
+		if !node.removed {
+			live = append(live, node)
+		}
+	}
+
+	return live
+}
+
+// targets returns instructions which are jumped to or start or end handler ranges.
+func (o *peepholeOptimizer) targets() map[*peepholeNode]bool {
+	targets := make(map[*peepholeNode]bool)
+	for _, node := range o.live() {
+		if node.target != nil {
+			targets[node.target] = true
+		}
+	}
+
+	for _, h := range o.handlers {
+		targets[h.start] = true
+		targets[h.end] = true
+		targets[h.target] = true
+	}
+
+	return targets
+}
+
+// remove removes instructions. Jumps and handlers to them go to the instruction
+// following them and their source lines move along.
+func (o *peepholeOptimizer) remove(nodes ...*peepholeNode) {
+	for _, node := range nodes {
+		node.removed = true
+	}
+
+	live := append(o.live(), o.end)
+	next := make(map[*peepholeNode]*peepholeNode)
+
+	for i, j := len(o.nodes)-1, len(live)-2; i >= 0; i-- {
+		if j >= 0 && o.nodes[i] == live[j] {
+			j--
+			continue
+		}
+		next[o.nodes[i]] = live[j+1]
+	}
+
+	moved := make(map[*peepholeNode][]int)
+	for _, node := range nodes {
+		moved[next[node]] = append(moved[next[node]], node.lines...)
+		node.lines = nil
+	}
+
+	for following, lines := range moved {
+		following.lines = append(lines, following.lines...)
+	}
+
+	for _, node := range live {
+		for node.target != nil && node.target.removed {
+			node.target = next[node.target]
+		}
+	}
+
+	for _, h := range o.handlers {
+		for _, n := range []**peepholeNode{&h.start, &h.end, &h.target} {
+			for (*n).removed {
+				*n = next[*n]
+			}
+		}
+	}
+}
+
+// optimize applies rules until one of them changes instructions and returns
+// whether anything changed.
+func (o *peepholeOptimizer) optimize() bool {
+	changed := false
+	live := o.live()
+
+	// thread jumps to unconditional jumps, jump cycles are left alone
+	for _, node := range live {
+		for hops := 0; hops < len(live); hops++ {
+			target := node.target
+			if target == nil || target == node || target.op != OpJump || target.target == target {
+				break
+			}
+
+			node.target = target.target
+			changed = true
+		}
+	}
+
+	targets := o.targets()
+
+	// removing instructions changes jump targets, so start over after every change
+	for i, node := range live {
+		next := o.end
+		if i+1 < len(live) {
+			next = live[i+1]
+		}
+
+		switch {
+		case node.op == OpJump && node.target == next:
+			o.remove(node)
+			return true
+		case isPurePush(node.op) && next.op == OpPop && next != o.end && !targets[next]:
+			o.remove(node, next)
+			return true
+		case i+2 < len(live) && !targets[live[i+1]] && !targets[live[i+2]]:
+			if o.fold(node, live[i+1], live[i+2]) || o.fuse(node, live[i+1], live[i+2]) {
+				return true
+			}
+		}
+	}
+
+	return changed
+}
+
+// fold replaces OpConstant OpConstant with arithmetic opcode by a constant of the result.
+func (o *peepholeOptimizer) fold(a, b, op *peepholeNode) bool {
+	if a.op != OpConstant || b.op != OpConstant {
+		return false
+	}
+
+	constants := o.bytecode.Constants
+	if a.operands[0] >= len(constants) || b.operands[0] >= len(constants) {
+		return false
+	}
+
+	result, ok := foldConstants(op.op, constants[a.operands[0]], constants[b.operands[0]])
+	if !ok {
+		return false
+	}
+
+	a.operands = []int{o.constant(result)}
+	o.remove(b, op)
+	return true
+}
+
+// fuse replaces OpGetLocal OpConstant OpAdd by OpAddLocalConstant.
+func (o *peepholeOptimizer) fuse(local, constant, add *peepholeNode) bool {
+	if local.op != OpGetLocal || constant.op != OpConstant || add.op != OpAdd {
+		return false
+	}
+
+	local.op = OpAddLocalConstant
+	local.operands = []int{local.operands[0], constant.operands[0]}
+	o.remove(constant, add)
+	return true
+}
+
+// constant returns index of constant in constant pool, appending it if missing.
+func (o *peepholeOptimizer) constant(c Constant) int {
+	for i, existing := range o.bytecode.Constants {
+		if existing == c {
+			return i
+		}
+	}
+
+	o.bytecode.Constants = append(o.bytecode.Constants, c)
+	return len(o.bytecode.Constants) - 1
+}
+
+// foldConstants computes arithmetic on constants. It returns false when the result
+// is not known at compile time, which includes integer overflow.
+func foldConstants(op Opcode, left, right Constant) (Constant, bool) {
+	if l, ok := left.(StringConstant); ok {
+		if r, ok := right.(StringConstant); ok && op == OpAdd {
+			return l + r, true
+		}
+		return nil, false
+	}
+
+	l, ok := left.(IntegerConstant)
+	if !ok {
+		return nil, false
+	}
+
+	r, ok := right.(IntegerConstant)
+	if !ok {
+		return nil, false
+	}
+
+	result, ok := FoldIntegers(op, int64(l), int64(r))
+	return IntegerConstant(result), ok
+}
+
+// FoldIntegers computes arithmetic opcode on integers at compile time. It returns
+// false when the result is only known at runtime, which includes integer overflow
+// and division by zero, so that folding never hides a runtime error.
+func FoldIntegers(op Opcode, l, r int64) (int64, bool) {
+	switch op {
+	case OpAdd:
+		result := l + r
+		return result, (l >= 0) != (r >= 0) || (result >= 0) == (l >= 0)
+	case OpSub:
+		result := l - r
+		return result, (l >= 0) == (r >= 0) || (result >= 0) == (l >= 0)
+	case OpMul:
+		if l == 0 || r == 0 {
+			return 0, true
+		}
+		result := l * r
+		return result, result/r == l && !(l == -1 && r == math.MinInt64) && !(r == -1 && l == math.MinInt64)
+	case OpDiv:
+		if r == 0 || (l == math.MinInt64 && r == -1) {
+			return 0, false
+		}
+		return l / r, true
+	}
+
+	return 0, false
+}
+
+// encode encodes live instructions resolving jump targets. Jumps start narrow and
+// become wide when offsets grow until offsets settle. Handlers whose instructions
+// were all removed are dropped.
+func (o *peepholeOptimizer) encode() (Instructions, []Handler, []Line, error) {
+	live := o.live()
+
+	for changed := true; changed; {
+		changed = false
+
+		offset := 0
+		for _, node := range live {
+			if node.offset != offset {
+				node.offset = offset
+				changed = true
+			}
+			offset += len(o.make(node))
+		}
+
+		if o.end.offset != offset {
+			o.end.offset = offset
+			changed = true
+		}
+	}
+
+	ins := Instructions{}
+	lines := []Line{}
+
+	for _, node := range append(live, o.end) {
+		if len(node.lines) > 0 {
+			line := node.lines[len(node.lines)-1]
+			if len(lines) == 0 || lines[len(lines)-1].Line != line {
+				lines = append(lines, Line{Offset: node.offset, Line: line})
+			}
+		}
+
+		if node == o.end {
+			continue
+		}
+
+		instruction := o.make(node)
+		if len(instruction) == 0 {
+			return nil, nil, nil, fmt.Errorf("operands of %s at offset %d do not fit", node.op, node.offset)
+		}
+		ins = append(ins, instruction...)
+	}
+
+	handlers := []Handler{}
+	for _, h := range o.handlers {
+		if h.start != h.end {
+			handlers = append(handlers, Handler{Start: h.start.offset, End: h.end.offset, Target: h.target.offset})
+		}
+	}
+
+	return ins, handlers, lines, nil
+}
+
+func (o *peepholeOptimizer) make(node *peepholeNode) []byte {
+	if node.target != nil {
+		return Make(node.op, node.target.offset)
+	}
+
+	return Make(node.op, node.operands...)
+}
@@ -0,0 +1,193 @@
+package jlang
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPeephole(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			// jump to next instruction
+			"OpTrue\nOpJump next\nnext: OpSetGlobal 0",
+			"OpTrue\nOpSetGlobal 0",
+		},
+		{
+			// jump to jump
+			"OpTrue\nOpJumpNotTruthy a\nOpNull\nOpPop\na: OpJump b\nOpNull\nb: OpTrue\nOpSetGlobal 0",
+			"OpTrue\nOpJumpNotTruthy b\nOpJump b\nOpNull\nb: OpTrue\nOpSetGlobal 0",
+		},
+		{
+			".const 1\n.const 2\nOpConstant 0\nOpConstant 1\nOpAdd\nOpSetGlobal 0",
+			".const 1\n.const 2\n.const 3\nOpConstant 2\nOpSetGlobal 0",
+		},
+		{
+			// folding reuses existing constant and repeats
+			".const 2\n.const 3\n.const 5\n.const 10\nOpConstant 0\nOpConstant 1\nOpAdd\nOpConstant 2\nOpMul\nOpSetGlobal 0",
+			".const 2\n.const 3\n.const 5\n.const 10\n.const 25\nOpConstant 4\nOpSetGlobal 0",
+		},
+		{
+			`.const "a"` + "\n" + `.const "b"` + "\nOpConstant 0\nOpConstant 1\nOpAdd\nOpSetGlobal 0",
+			`.const "a"` + "\n" + `.const "b"` + "\n" + `.const "ab"` + "\nOpConstant 2\nOpSetGlobal 0",
+		},
+		{
+			// overflow is left to runtime
+			".const 9223372036854775807\n.const 1\nOpConstant 0\nOpConstant 1\nOpAdd\nOpSetGlobal 0",
+			".const 9223372036854775807\n.const 1\nOpConstant 0\nOpConstant 1\nOpAdd\nOpSetGlobal 0",
+		},
+		{
+			".const 7\n.const 2\nOpConstant 0\nOpConstant 1\nOpDiv\nOpSetGlobal 0",
+			".const 7\n.const 2\n.const 3\nOpConstant 2\nOpSetGlobal 0",
+		},
+		{
+			// division by zero is left to runtime
+			".const 1\n.const 0\nOpConstant 0\nOpConstant 1\nOpDiv\nOpSetGlobal 0",
+			".const 1\n.const 0\nOpConstant 0\nOpConstant 1\nOpDiv\nOpSetGlobal 0",
+		},
+		{
+			// operand is jumped to
+			".const 1\n.const 2\nOpConstant 0\nOpTrue\nOpJumpNotTruthy a\nOpConstant 1\nOpPop\na: OpConstant 1\nOpAdd\nOpSetGlobal 0",
+			".const 1\n.const 2\nOpConstant 0\nOpTrue\nOpJumpNotTruthy a\na: OpConstant 1\nOpAdd\nOpSetGlobal 0",
+		},
+		{
+			"OpNull\nOpPop\nOpTrue\nOpPop\nOpGetGlobal 1\nOpPop",
+			"",
+		},
+		{
+			".const 1\n.func f 1 1\nOpGetLocal 0\nOpConstant 0\nOpAdd\nOpReturnValue\n.end",
+			".const 1\n.func f 1 1\nOpAddLocalConstant 0 0\nOpReturnValue\n.end",
+		},
+		{
+			// jump cycle
+			"a: OpJump b\nb: OpJump a",
+			"a: OpJump a\nOpJump a",
+		},
+	}
+
+	for _, tt := range tests {
+		bytecode, err := AssembleBytecode(tt.input)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		expected, err := AssembleBytecode(tt.expected)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if err := Peephole(bytecode); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+
+		if string(bytecode.Instructions) != string(expected.Instructions) {
+			t.Errorf("instructions wrong for %q.\nwant=%q\ngot=%q",
+				tt.input, expected.Instructions.String(), bytecode.Instructions.String())
+		}
+
+		if !reflect.DeepEqual(bytecode.Constants, expected.Constants) {
+			t.Errorf("constants wrong for %q. expected=%v, got=%v", tt.input, expected.Constants, bytecode.Constants)
+		}
+
+		for i, fn := range expected.Functions {
+			if string(bytecode.Functions[i].Instructions) != string(fn.Instructions) {
+				t.Errorf("instructions of %s wrong.\nwant=%q\ngot=%q",
+					fn.Name, fn.Instructions.String(), bytecode.Functions[i].Instructions.String())
+			}
+		}
+	}
+}
+
+func TestPeephole_Lines(t *testing.T) {
+	bytecode, err := AssembleBytecode(`
+	.const 1
+	.const 2
+	OpTrue
+	OpJumpNotTruthy end
+	OpConstant 0
+	OpConstant 1
+	OpAdd
+	OpSetGlobal 0
+	OpNull
+	OpPop
+	end:
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// line 3 starts at folded OpConstant 1 and moves to OpSetGlobal,
+	// line 4 starts at removed OpNull and moves to the end
+	bytecode.Lines = []Line{{Offset: 0, Line: 1}, {Offset: 4, Line: 2}, {Offset: 7, Line: 3}, {Offset: 14, Line: 4}}
+
+	if err := Peephole(bytecode); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := Verify(bytecode); err != nil {
+		t.Fatalf("optimized bytecode does not verify: %s", err)
+	}
+
+	expectedIns := concat(Make(OpTrue), Make(OpJumpNotTruthy, 10), Make(OpConstant, 2), Make(OpSetGlobal, 0))
+	if string(bytecode.Instructions) != string(expectedIns) {
+		t.Errorf("instructions wrong.\nwant=%q\ngot=%q", expectedIns.String(), bytecode.Instructions.String())
+	}
+
+	expected := []Line{{Offset: 0, Line: 1}, {Offset: 4, Line: 2}, {Offset: 7, Line: 3}, {Offset: 10, Line: 4}}
+	if !reflect.DeepEqual(bytecode.Lines, expected) {
+		t.Errorf("lines wrong. expected=%v, got=%v", expected, bytecode.Lines)
+	}
+}
+
+func TestPeephole_Handlers(t *testing.T) {
+	bytecode, err := AssembleBytecode(`
+	.const 1
+	.const 2
+	OpNull
+	OpPop
+	OpConstant 0
+	OpConstant 1     ; handler starts here, so constants are not folded
+	OpAdd
+	OpSetGlobal 0
+	OpJump end       ; handler ends here
+	OpSetGlobal 1    ; handler target
+	end:
+	`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// second handler covers only removed instructions
+	bytecode.Handlers = []Handler{{Start: 5, End: 12, Target: 15}, {Start: 0, End: 2, Target: 15}}
+
+	if err := Verify(bytecode); err != nil {
+		t.Fatalf("bytecode does not verify: %s", err)
+	}
+
+	if err := Peephole(bytecode); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := Verify(bytecode); err != nil {
+		t.Fatalf("optimized bytecode does not verify: %s", err)
+	}
+
+	expectedIns := concat(
+		Make(OpConstant, 0),
+		Make(OpConstant, 1),
+		Make(OpAdd),
+		Make(OpSetGlobal, 0),
+		Make(OpJump, 16),
+		Make(OpSetGlobal, 1),
+	)
+	if string(bytecode.Instructions) != string(expectedIns) {
+		t.Errorf("instructions wrong.\nwant=%q\ngot=%q", expectedIns.String(), bytecode.Instructions.String())
+	}
+
+	expected := []Handler{{Start: 3, End: 10, Target: 13}}
+	if !reflect.DeepEqual(bytecode.Handlers, expected) {
+		t.Errorf("handlers wrong. expected=%v, got=%v", expected, bytecode.Handlers)
+	}
+}
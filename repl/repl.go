@@ -35,6 +35,9 @@ func Start(in io.Reader, out io.Writer) {
 		if len(p.Errors()) != 0 {
 			printParserErrors(out, p.Errors())
 		}
+		if len(p.Warnings()) != 0 {
+			printParserWarnings(out, p.Warnings())
+		}
 		io.WriteString(out, program.String())
 		io.WriteString(out, "\n")
 	}
@@ -45,3 +48,9 @@ func printParserErrors(out io.Writer, errors []string) {
 		io.WriteString(out, "\t"+msg+"\n")
 	}
 }
+
+func printParserWarnings(out io.Writer, warnings []string) {
+	for _, msg := range warnings {
+		io.WriteString(out, "\twarning: "+msg+"\n")
+	}
+}
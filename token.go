@@ -5,8 +5,13 @@ const (
 	EOF     TokenType = "EOF"
 
 	// Identifiers + literals
-	IDENT TokenType = "IDENT" // add, foobar, x, y, ...
-	INT   TokenType = "INT"   // 1343456
+	IDENT  TokenType = "IDENT"  // add, foobar, x, y, ...
+	INT    TokenType = "INT"    // 1343456
+	STRING TokenType = "STRING" // "foo"
+
+	// STRING_PART is a string segment followed by an interpolated expression
+	// "hello ${name}!" will be STRING_PART("hello ") IDENT(name) STRING("!")
+	STRING_PART TokenType = "STRING_PART"
 
 	// Operators
 	ASSIGN   TokenType = "="
@@ -22,15 +27,31 @@ const (
 	EQ     TokenType = "=="
 	NOT_EQ TokenType = "!="
 
+	ARROW    TokenType = "=>"
+	PIPE     TokenType = "|>"
+	QUESTION TokenType = "?"
+
+	QUESTION_DOT TokenType = "?."
+	NULLISH      TokenType = "??"
+
+	RANGE           TokenType = ".."
+	RANGE_INCLUSIVE TokenType = "..="
+
 	// Delimiters
 	COMMA     TokenType = ","
 	SEMICOLON TokenType = ";"
+	COLON     TokenType = ":"
+	DOT       TokenType = "."
+	ELLIPSIS  TokenType = "..."
 
 	LPAREN TokenType = "("
 	RPAREN TokenType = ")"
 	LBRACE TokenType = "{"
 	RBRACE TokenType = "}"
 
+	LBRACKET TokenType = "["
+	RBRACKET TokenType = "]"
+
 	// Keywords
 	FUNCTION TokenType = "FUNCTION"
 	LET      TokenType = "LET"
@@ -39,16 +60,44 @@ const (
 	IF       TokenType = "IF"
 	ELSE     TokenType = "ELSE"
 	RETURN   TokenType = "RETURN"
+	MATCH    TokenType = "MATCH"
+	STRUCT   TokenType = "STRUCT"
+	ENUM     TokenType = "ENUM"
+	THROW    TokenType = "THROW"
+	TRY      TokenType = "TRY"
+	CATCH    TokenType = "CATCH"
+	FINALLY  TokenType = "FINALLY"
+	YIELD    TokenType = "YIELD"
+	FOR      TokenType = "FOR"
+	IN       TokenType = "IN"
+	NULL     TokenType = "NULL"
+	IMPORT   TokenType = "IMPORT"
+	EXPORT   TokenType = "EXPORT"
+	AS       TokenType = "AS"
 )
 
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":      FUNCTION,
+	"let":     LET,
+	"true":    TRUE,
+	"false":   FALSE,
+	"if":      IF,
+	"else":    ELSE,
+	"return":  RETURN,
+	"match":   MATCH,
+	"struct":  STRUCT,
+	"enum":    ENUM,
+	"throw":   THROW,
+	"try":     TRY,
+	"catch":   CATCH,
+	"finally": FINALLY,
+	"yield":   YIELD,
+	"for":     FOR,
+	"in":      IN,
+	"null":    NULL,
+	"import":  IMPORT,
+	"export":  EXPORT,
+	"as":      AS,
 }
 
 type TokenType string
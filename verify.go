@@ -0,0 +1,303 @@
+package jlang
+
+import (
+	"fmt"
+)
+
+// LoadBytecode decodes bytecode in .jbc format and verifies it.
+func LoadBytecode(data []byte) (*Bytecode, error) {
+	bytecode := &Bytecode{}
+	if err := bytecode.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+
+	if err := Verify(bytecode); err != nil {
+		return nil, err
+	}
+
+	return bytecode, nil
+}
+
+// Verify checks that bytecode is safe to run. Every instruction must be a defined
+// opcode with all of its operands, jumps must land on instruction boundaries,
+// constant, global and local indexes must be in range of constant pool, GlobalsSize
+// and locals of function, which are at most MaxLocals, and every path reaching an
+// instruction must do so with the same stack depth, which never underflows or exceeds StackSize.
+// Handlers must cover instructions of their own code and their targets are entered
+// with the exception as the only value on stack.
+func Verify(b *Bytecode) error {
+	for i, c := range b.Constants {
+		if fn, ok := c.(FunctionConstant); ok && (int(fn) < 0 || int(fn) >= len(b.Functions)) {
+			return fmt.Errorf("invalid bytecode: constant %d refers to undefined function %d", i, fn)
+		}
+	}
+
+	if err := verifyCode(b, "top level code", b.Instructions, 0, b.Handlers, b.Lines, false); err != nil {
+		return err
+	}
+
+	for i, fn := range b.Functions {
+		name := fmt.Sprintf("function %s", fn.Name)
+		if fn.Name == "" {
+			name = fmt.Sprintf("function #%d", i)
+		}
+
+		if fn.NumLocals > MaxLocals {
+			return fmt.Errorf("invalid bytecode in %s: %d locals exceed maximum of %d",
+				name, fn.NumLocals, MaxLocals)
+		}
+
+		if fn.NumParams > fn.NumLocals {
+			return fmt.Errorf("invalid bytecode in %s: %d parameters do not fit in %d locals",
+				name, fn.NumParams, fn.NumLocals)
+		}
+
+		if err := verifyCode(b, name, fn.Instructions, fn.NumLocals, fn.Handlers, fn.Lines, true); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type codeVerifier struct {
+	bytecode   *Bytecode
+	name       string
+	ins        Instructions
+	numLocals  int
+	handlers   []Handler
+	isFunction bool
+
+	instructions []*Instruction
+
+	// index of instruction at each instruction boundary
+	boundaries map[int]int
+}
+
+func verifyCode(b *Bytecode, name string, ins Instructions, numLocals int, handlers []Handler, lines []Line, isFunction bool) error {
+	v := &codeVerifier{
+		bytecode:     b,
+		name:         name,
+		ins:          ins,
+		numLocals:    numLocals,
+		handlers:     handlers,
+		isFunction:   isFunction,
+		instructions: []*Instruction{},
+		boundaries:   make(map[int]int),
+	}
+
+	if err := v.decode(); err != nil {
+		return err
+	}
+
+	if err := v.checkOperands(); err != nil {
+		return err
+	}
+
+	if err := v.checkHandlers(); err != nil {
+		return err
+	}
+
+	if err := v.checkStack(); err != nil {
+		return err
+	}
+
+	for _, l := range lines {
+		if _, ok := v.boundaries[l.Offset]; !ok && l.Offset != len(ins) {
+			return v.errorf(l.Offset, "line table entry is not at instruction boundary")
+		}
+	}
+
+	return nil
+}
+
+func (v *codeVerifier) errorf(offset int, format string, args ...interface{}) error {
+	return fmt.Errorf("invalid bytecode in %s at offset %d: %s",
+		v.name, offset, fmt.Sprintf(format, args...))
+}
+
+// decode splits instructions checking that opcodes are defined and operands are not truncated.
+func (v *codeVerifier) decode() error {
+	for offset := 0; offset < len(v.ins); {
+		ins, err := ReadInstruction(v.ins, offset)
+		if err != nil {
+			return v.errorf(offset, "%s", err)
+		}
+
+		v.boundaries[offset] = len(v.instructions)
+		v.instructions = append(v.instructions, ins)
+
+		offset = ins.Next
+	}
+
+	return nil
+}
+
+// checkOperands checks jump targets and indexes of constants, globals and locals.
+func (v *codeVerifier) checkOperands() error {
+	for _, ins := range v.instructions {
+		switch ins.Opcode {
+		case OpJump, OpJumpNotTruthy:
+			target := ins.Operands[0]
+			if _, ok := v.boundaries[target]; !ok && target != len(v.ins) {
+				return v.errorf(ins.Offset, "jump target %d is not an instruction boundary", target)
+			}
+		case OpConstant:
+			if ins.Operands[0] >= len(v.bytecode.Constants) {
+				return v.errorf(ins.Offset, "constant %d out of range, constant pool has %d constants",
+					ins.Operands[0], len(v.bytecode.Constants))
+			}
+		case OpAddLocalConstant:
+			if ins.Operands[0] >= v.numLocals {
+				return v.errorf(ins.Offset, "local %d out of range, %s has %d locals",
+					ins.Operands[0], v.name, v.numLocals)
+			}
+
+			if ins.Operands[1] >= len(v.bytecode.Constants) {
+				return v.errorf(ins.Offset, "constant %d out of range, constant pool has %d constants",
+					ins.Operands[1], len(v.bytecode.Constants))
+			}
+		case OpHash:
+			if ins.Operands[0]%2 != 0 {
+				return v.errorf(ins.Offset, "hash needs key and value pairs, got %d elements", ins.Operands[0])
+			}
+		case OpGetGlobal, OpSetGlobal:
+			if ins.Operands[0] >= GlobalsSize {
+				return v.errorf(ins.Offset, "global %d out of range, globals store has %d slots",
+					ins.Operands[0], GlobalsSize)
+			}
+		case OpGetLocal, OpSetLocal:
+			if ins.Operands[0] >= v.numLocals {
+				return v.errorf(ins.Offset, "local %d out of range, %s has %d locals",
+					ins.Operands[0], v.name, v.numLocals)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkHandlers checks that handlers cover a range of instructions and jump to an instruction.
+func (v *codeVerifier) checkHandlers() error {
+	for i, h := range v.handlers {
+		if h.Start >= h.End {
+			return v.errorf(h.Start, "handler %d covers no instructions, it ends at %d", i, h.End)
+		}
+
+		if _, ok := v.boundaries[h.Start]; !ok {
+			return v.errorf(h.Start, "handler %d start is not an instruction boundary", i)
+		}
+
+		if _, ok := v.boundaries[h.End]; !ok && h.End != len(v.ins) {
+			return v.errorf(h.End, "handler %d end is not an instruction boundary", i)
+		}
+
+		if _, ok := v.boundaries[h.Target]; !ok {
+			return v.errorf(h.Target, "handler %d target is not an instruction", i)
+		}
+	}
+
+	return nil
+}
+
+// checkStack follows every path from the first instruction and handler targets and
+// checks that stack depth agrees wherever paths join.
+func (v *codeVerifier) checkStack() error {
+	if len(v.instructions) == 0 {
+		if v.isFunction {
+			return v.errorf(0, "%s has no return", v.name)
+		}
+		return nil
+	}
+
+	depths := make([]int, len(v.instructions))
+	for i := range depths {
+		depths[i] = -1
+	}
+
+	depths[0] = 0
+	worklist := []int{0}
+
+	// handler targets start with the exception on stack
+	for _, h := range v.handlers {
+		j := v.boundaries[h.Target]
+		if depths[j] == -1 {
+			depths[j] = 1
+			worklist = append(worklist, j)
+		} else if depths[j] != 1 {
+			return v.errorf(h.Target, "inconsistent stack depth, %d on one path and 1 on another", depths[j])
+		}
+	}
+
+	for len(worklist) > 0 {
+		i := worklist[len(worklist)-1]
+		worklist = worklist[:len(worklist)-1]
+
+		ins := v.instructions[i]
+		pops, pushes := stackEffect(ins.Opcode, ins.Operands)
+
+		if depths[i] < pops {
+			return v.errorf(ins.Offset, "stack underflow, %s needs %d values but stack has %d",
+				ins.Desc.Name, pops, depths[i])
+		}
+
+		depth := depths[i] - pops + pushes
+		if depth > StackSize {
+			return v.errorf(ins.Offset, "stack overflow, depth exceeds %d", StackSize)
+		}
+
+		for _, target := range successors(ins) {
+			if target == len(v.ins) {
+				if v.isFunction {
+					return v.errorf(ins.Offset, "%s falls off end without return", v.name)
+				}
+				continue
+			}
+
+			j := v.boundaries[target]
+			if depths[j] == -1 {
+				depths[j] = depth
+				worklist = append(worklist, j)
+			} else if depths[j] != depth {
+				return v.errorf(target, "inconsistent stack depth, %d on one path and %d on another",
+					depths[j], depth)
+			}
+		}
+	}
+
+	return nil
+}
+
+// successors returns offsets of instructions which may run after ins.
+func successors(ins *Instruction) []int {
+	switch ins.Opcode {
+	case OpJump:
+		return []int{ins.Operands[0]}
+	case OpJumpNotTruthy:
+		return []int{ins.Next, ins.Operands[0]}
+	case OpReturnValue, OpReturn:
+		return []int{}
+	}
+
+	return []int{ins.Next}
+}
+
+// stackEffect returns number of values instruction pops from and pushes onto stack.
+func stackEffect(op Opcode, operands []int) (int, int) {
+	switch op {
+	case OpConstant, OpTrue, OpFalse, OpNull, OpGetGlobal, OpGetLocal, OpAddLocalConstant:
+		return 0, 1
+	case OpPop, OpJumpNotTruthy, OpSetGlobal, OpSetLocal, OpReturnValue:
+		return 1, 0
+	case OpAdd, OpSub, OpMul, OpDiv, OpEqual, OpNotEqual, OpGreaterThan, OpIndex:
+		return 2, 1
+	case OpMinus, OpBang:
+		return 1, 1
+	case OpArray, OpHash:
+		return operands[0], 1
+	case OpCall:
+		return operands[0] + 1, 1
+	}
+
+	return 0, 0
+}
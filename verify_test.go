@@ -0,0 +1,341 @@
+package jlang
+
+import (
+	"testing"
+)
+
+func concat(instructions ...[]byte) Instructions {
+	out := Instructions{}
+	for _, ins := range instructions {
+		out = append(out, ins...)
+	}
+
+	return out
+}
+
+func TestVerify(t *testing.T) {
+	constants := []Constant{IntegerConstant(1), FunctionConstant(0)}
+	add := &CompiledFunction{
+		Name:      "add",
+		NumParams: 2,
+		NumLocals: 2,
+		Instructions: concat(
+			Make(OpGetLocal, 0),
+			Make(OpGetLocal, 1),
+			Make(OpAdd),
+			Make(OpReturnValue),
+		),
+	}
+
+	tests := []struct {
+		instructions  Instructions
+		expectedError string
+	}{
+		{Instructions{}, ""},
+		{
+			// if (true) { 1 } else { null }; add(1, 1)
+			concat(
+				Make(OpTrue),
+				Make(OpJumpNotTruthy, 10),
+				Make(OpConstant, 0),
+				Make(OpJump, 11),
+				Make(OpNull),
+				Make(OpPop),
+				Make(OpConstant, 1),
+				Make(OpConstant, 0),
+				Make(OpConstant, 0),
+				Make(OpCall, 2),
+				Make(OpPop),
+			),
+			"",
+		},
+		{
+			Instructions{255},
+			"invalid bytecode in top level code at offset 0: opcode 255 undefined",
+		},
+		{
+			Instructions{byte(OpConstant), 0},
+			"invalid bytecode in top level code at offset 0: operands of OpConstant are truncated",
+		},
+		{
+			concat(Make(OpJump, 2), Make(OpNull)),
+			"invalid bytecode in top level code at offset 0: jump target 2 is not an instruction boundary",
+		},
+		{
+			concat(Make(OpJump, 100)),
+			"invalid bytecode in top level code at offset 0: jump target 100 is not an instruction boundary",
+		},
+		{
+			concat(Make(OpConstant, 2)),
+			"invalid bytecode in top level code at offset 0: constant 2 out of range, constant pool has 2 constants",
+		},
+		{
+			concat(Make(OpGetLocal, 0)),
+			"invalid bytecode in top level code at offset 0: local 0 out of range, top level code has 0 locals",
+		},
+		{
+			concat(Make(OpNull), Make(OpHash, 1)),
+			"invalid bytecode in top level code at offset 1: hash needs key and value pairs, got 1 elements",
+		},
+		{
+			concat(Make(OpConstant, 0), Make(OpAdd)),
+			"invalid bytecode in top level code at offset 3: stack underflow, OpAdd needs 2 values but stack has 1",
+		},
+		{
+			concat(Make(OpPop)),
+			"invalid bytecode in top level code at offset 0: stack underflow, OpPop needs 1 values but stack has 0",
+		},
+		{
+			// loop pushing a value on every iteration
+			concat(Make(OpNull), Make(OpJump, 0)),
+			"invalid bytecode in top level code at offset 0: inconsistent stack depth, 0 on one path and 1 on another",
+		},
+		{
+			// only one branch pushes a value
+			concat(
+				Make(OpTrue),
+				Make(OpJumpNotTruthy, 7),
+				Make(OpConstant, 0),
+				Make(OpNull),
+			),
+			"invalid bytecode in top level code at offset 7: inconsistent stack depth, 0 on one path and 1 on another",
+		},
+	}
+
+	for i, tt := range tests {
+		bytecode := &Bytecode{
+			Instructions: tt.instructions,
+			Constants:    constants,
+			Functions:    []*CompiledFunction{add},
+		}
+
+		err := Verify(bytecode)
+		if tt.expectedError == "" {
+			if err != nil {
+				t.Errorf("tests[%d] - unexpected error: %s", i, err)
+			}
+			continue
+		}
+
+		if err == nil {
+			t.Errorf("tests[%d] - expected error %q. got none", i, tt.expectedError)
+			continue
+		}
+
+		if err.Error() != tt.expectedError {
+			t.Errorf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, err.Error())
+		}
+	}
+}
+
+func TestVerify_Functions(t *testing.T) {
+	tests := []struct {
+		bytecode      *Bytecode
+		expectedError string
+	}{
+		{
+			&Bytecode{Constants: []Constant{FunctionConstant(1)}},
+			"invalid bytecode: constant 0 refers to undefined function 1",
+		},
+		{
+			&Bytecode{Functions: []*CompiledFunction{{Name: "f", NumParams: 2, NumLocals: 1}}},
+			"invalid bytecode in function f: 2 parameters do not fit in 1 locals",
+		},
+		{
+			&Bytecode{Functions: []*CompiledFunction{{Name: "f", NumLocals: 4000000000}}},
+			"invalid bytecode in function f: 4000000000 locals exceed maximum of 65536",
+		},
+		{
+			&Bytecode{Functions: []*CompiledFunction{{}}},
+			"invalid bytecode in function #0 at offset 0: function #0 has no return",
+		},
+		{
+			&Bytecode{Functions: []*CompiledFunction{{Name: "f", Instructions: concat(Make(OpNull), Make(OpPop))}}},
+			"invalid bytecode in function f at offset 1: function f falls off end without return",
+		},
+		{
+			&Bytecode{Functions: []*CompiledFunction{{
+				Name:         "f",
+				Instructions: concat(Make(OpNull), Make(OpReturnValue)),
+				Lines:        []Line{{Offset: 0, Line: 1}, {Offset: 3, Line: 2}},
+			}}},
+			"invalid bytecode in function f at offset 3: line table entry is not at instruction boundary",
+		},
+	}
+
+	for i, tt := range tests {
+		err := Verify(tt.bytecode)
+		if err == nil {
+			t.Errorf("tests[%d] - expected error %q. got none", i, tt.expectedError)
+			continue
+		}
+
+		if err.Error() != tt.expectedError {
+			t.Errorf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, err.Error())
+		}
+	}
+}
+
+func TestVerify_Handlers(t *testing.T) {
+	// fn f(x) { try { return x } catch (e) { return e } }
+	ins := concat(
+		Make(OpGetLocal, 0),
+		Make(OpReturnValue),
+		Make(OpReturnValue),
+	)
+
+	tests := []struct {
+		handlers      []Handler
+		expectedError string
+	}{
+		{[]Handler{{Start: 0, End: 2, Target: 3}}, ""},
+		{[]Handler{{Start: 0, End: 4, Target: 3}, {Start: 2, End: 3, Target: 3}}, ""},
+		{[]Handler{{Start: 2, End: 2, Target: 3}},
+			"invalid bytecode in function f at offset 2: handler 0 covers no instructions, it ends at 2"},
+		{[]Handler{{Start: 0, End: 2, Target: 3}, {Start: 1, End: 2, Target: 3}},
+			"invalid bytecode in function f at offset 1: handler 1 start is not an instruction boundary"},
+		{[]Handler{{Start: 0, End: 1, Target: 3}},
+			"invalid bytecode in function f at offset 1: handler 0 end is not an instruction boundary"},
+		{[]Handler{{Start: 0, End: 2, Target: 4}},
+			"invalid bytecode in function f at offset 4: handler 0 target is not an instruction"},
+		{[]Handler{{Start: 2, End: 3, Target: 0}},
+			"invalid bytecode in function f at offset 0: inconsistent stack depth, 0 on one path and 1 on another"},
+	}
+
+	for i, tt := range tests {
+		err := Verify(&Bytecode{Functions: []*CompiledFunction{{
+			Name:         "f",
+			NumParams:    1,
+			NumLocals:    1,
+			Instructions: ins,
+			Handlers:     tt.handlers,
+		}}})
+
+		if tt.expectedError == "" {
+			if err != nil {
+				t.Errorf("tests[%d] - unexpected error: %s", i, err)
+			}
+			continue
+		}
+
+		if err == nil || err.Error() != tt.expectedError {
+			t.Errorf("tests[%d] - error wrong. expected=%q, got=%v", i, tt.expectedError, err)
+		}
+	}
+
+	// try { null } catch (e) { e } in top level code
+	top := &Bytecode{
+		Instructions: concat(Make(OpNull), Make(OpPop), Make(OpJump, 6), Make(OpPop)),
+		Handlers:     []Handler{{Start: 0, End: 2, Target: 5}},
+	}
+	if err := Verify(top); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+// Malformed instructions must be reported as errors, never panic.
+func TestVerify_Malformed(t *testing.T) {
+	ins := concat(
+		Make(OpTrue),
+		Make(OpJumpNotTruthy, 10),
+		Make(OpConstant, 0),
+		Make(OpJump, 11),
+		Make(OpNull),
+		Make(OpPop),
+		Make(OpArray, 0),
+		Make(OpCall, 0),
+		Make(OpPop),
+	)
+
+	for i := range ins {
+		for _, b := range []byte{0, 1, byte(OpJump), byte(OpCall), byte(OpHash), byte(OpWide), 0x7F, 0xFF} {
+			mutated := append(Instructions{}, ins...)
+			mutated[i] = b
+
+			Verify(&Bytecode{Instructions: mutated, Constants: []Constant{NullConstant{}}})
+			Verify(&Bytecode{Instructions: mutated[:i]})
+		}
+	}
+}
+
+func TestVerify_Wide(t *testing.T) {
+	constants := make([]Constant, 70000)
+	for i := range constants {
+		constants[i] = IntegerConstant(i)
+	}
+
+	tests := []struct {
+		bytecode      *Bytecode
+		expectedError string
+	}{
+		{
+			&Bytecode{
+				Instructions: concat(Make(OpConstant, 69999), Make(OpPop)),
+				Constants:    constants,
+			},
+			"",
+		},
+		{
+			&Bytecode{Functions: []*CompiledFunction{{
+				Name:         "f",
+				NumLocals:    300,
+				Instructions: concat(Make(OpGetLocal, 299), Make(OpReturnValue)),
+			}}},
+			"",
+		},
+		{
+			&Bytecode{
+				Instructions: concat(Make(OpConstant, 70000), Make(OpPop)),
+				Constants:    constants,
+			},
+			"invalid bytecode in top level code at offset 0: constant 70000 out of range, constant pool has 70000 constants",
+		},
+		{
+			// jump into instruction after its wide prefix
+			&Bytecode{
+				Instructions: concat(Make(OpJump, 4), Make(OpConstant, 69999), Make(OpPop)),
+				Constants:    constants,
+			},
+			"invalid bytecode in top level code at offset 0: jump target 4 is not an instruction boundary",
+		},
+		{
+			&Bytecode{Instructions: concat(Make(OpGetGlobal, 65535), Make(OpPop))},
+			"",
+		},
+		{
+			&Bytecode{Instructions: concat(Make(OpGetGlobal, 4000000000), Make(OpPop))},
+			"invalid bytecode in top level code at offset 0: global 4000000000 out of range, globals store has 65536 slots",
+		},
+		{
+			&Bytecode{Functions: []*CompiledFunction{{
+				Name:         "f",
+				NumLocals:    MaxLocals,
+				Instructions: concat(Make(OpGetLocal, MaxLocals-1), Make(OpReturnValue)),
+			}}},
+			"",
+		},
+		{
+			&Bytecode{Instructions: Instructions{byte(OpWide), byte(OpPop)}},
+			"invalid bytecode in top level code at offset 0: OpWide is followed by OpPop which has no operands",
+		},
+	}
+
+	for i, tt := range tests {
+		err := Verify(tt.bytecode)
+		if tt.expectedError == "" {
+			if err != nil {
+				t.Errorf("tests[%d] - unexpected error: %s", i, err)
+			}
+			continue
+		}
+
+		if err == nil {
+			t.Errorf("tests[%d] - expected error %q. got none", i, tt.expectedError)
+			continue
+		}
+
+		if err.Error() != tt.expectedError {
+			t.Errorf("tests[%d] - error wrong. expected=%q, got=%q", i, tt.expectedError, err.Error())
+		}
+	}
+}
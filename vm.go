@@ -1,5 +1,14 @@
 package jlang
 
+// Maximum depth of operand stack
+const StackSize = 2048
+
+// Number of slots of globals store
+const GlobalsSize = 65536
+
+// Maximum number of locals of a function, as many as wide local operand addresses
+const MaxLocals = 65536
+
 type VM struct {
 	rawByteCode []byte
 }